@@ -0,0 +1,207 @@
+package admission
+
+import (
+	"crypto/x509"
+	"net"
+	"sync"
+
+	"github.com/quic-go/quic-go"
+	qpErr "github.com/quic-s/quics-protocol/pkg/error"
+	qpTls "github.com/quic-s/quics-protocol/pkg/tls"
+)
+
+// ConnectionInfo is the information of an accepted connection known before any transaction arrives.
+type ConnectionInfo struct {
+	// RemoteAddr is the address of the peer.
+	RemoteAddr net.Addr
+	// IP is the IP address of the peer.
+	IP net.IP
+	// ServerName is the server name indication (SNI) sent by the peer.
+	ServerName string
+	// NegotiatedProtocol is the application protocol negotiated by ALPN.
+	NegotiatedProtocol string
+	// PeerCertificates are the certificates sent by the peer. It is empty if the peer did not send a client certificate.
+	PeerCertificates []*x509.Certificate
+	// VerifiedChains are the chains of the peer certificate verified by the TLS handshake.
+	// It is empty if the client certificate is not verified, like with tls.RequireAnyClientCert.
+	VerifiedChains [][]*x509.Certificate
+}
+
+// Admission decides which connections are accepted by the server.
+// The rules are checked in order, and a connection is rejected by the first rule it does not pass:
+// Deny, Allow, Revocation, MaxConnectionsPerIP and AcceptConnection.
+type Admission struct {
+	// Deny rejects the connections from the IP addresses in these networks.
+	Deny []*net.IPNet
+	// Allow accepts only the connections from the IP addresses in these networks. Empty means all addresses.
+	Allow []*net.IPNet
+	// Revocation rejects the connections with a revoked client certificate.
+	Revocation *qpTls.Revocation
+	// MaxConnectionsPerIP is the maximum number of connections open from an IP address at the same time.
+	// A limit of 0 or less means unlimited.
+	MaxConnectionsPerIP int
+	// AcceptConnection is called with the information of the connection after the other rules passed.
+	// The connection is rejected if it returns an error.
+	AcceptConnection func(info *ConnectionInfo) error
+}
+
+// ParseCIDRs parses the CIDR notations, like "192.168.0.0/16" or "2001:db8::/32", for Admission.Allow and Admission.Deny.
+func ParseCIDRs(cidrs ...string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// Controller checks the accepted connections with an admission setting and counts the connections of each IP address.
+// This type is used internally by quics-protocol.
+// So, you may don't need to use it directly.
+type Controller struct {
+	mutex     sync.Mutex
+	admission Admission
+	conns     map[string]int
+}
+
+// NewController creates a new controller without any rule, so all connections are accepted.
+func NewController() *Controller {
+	return &Controller{
+		conns: make(map[string]int),
+	}
+}
+
+// SetAdmission sets the admission rules. The rules are applied to the connections accepted after it is called.
+func (c *Controller) SetAdmission(admission Admission) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.admission = admission
+}
+
+// Admit checks the connection with the admission rules.
+// If the connection is admitted, it is counted for its IP address until the connection is closed.
+// If it is rejected, the connection is closed with qpErr.ConnectionRejectedCode and the reason is returned.
+func (c *Controller) Admit(conn quic.Connection) error {
+	state := conn.ConnectionState().TLS
+	info := &ConnectionInfo{
+		RemoteAddr:         conn.RemoteAddr(),
+		IP:                 remoteIP(conn.RemoteAddr()),
+		ServerName:         state.ServerName,
+		NegotiatedProtocol: state.NegotiatedProtocol,
+		PeerCertificates:   state.PeerCertificates,
+		VerifiedChains:     state.VerifiedChains,
+	}
+
+	err := c.admit(info)
+	if err != nil {
+		conn.CloseWithError(qpErr.ConnectionRejectedCode, err.Error())
+		return err
+	}
+	go func() {
+		<-conn.Context().Done()
+		c.release(info.IP.String())
+	}()
+	return nil
+}
+
+func (c *Controller) admit(info *ConnectionInfo) error {
+	c.mutex.Lock()
+	admission := c.admission
+	c.mutex.Unlock()
+
+	if contains(admission.Deny, info.IP) {
+		return qpErr.ErrConnectionDenied
+	}
+	if len(admission.Allow) > 0 && !contains(admission.Allow, info.IP) {
+		return qpErr.ErrConnectionDenied
+	}
+	if admission.Revocation != nil {
+		err := admission.Revocation.Check(revocationCerts(info))
+		if err != nil {
+			return err
+		}
+	}
+
+	// The connection is counted before the hook is called,
+	// so connections from the same address accepted at the same time are limited too.
+	key := info.IP.String()
+	c.mutex.Lock()
+	if admission.MaxConnectionsPerIP > 0 && c.conns[key] >= admission.MaxConnectionsPerIP {
+		c.mutex.Unlock()
+		return qpErr.ErrTooManyConnections
+	}
+	c.conns[key]++
+	c.mutex.Unlock()
+
+	if admission.AcceptConnection != nil {
+		err := admission.AcceptConnection(info)
+		if err != nil {
+			c.release(key)
+			return err
+		}
+	}
+	return nil
+}
+
+// revocationCerts returns the certificates of the peer checked for revocation.
+// These are the certificates of the verified chains, because the other certificates sent by the peer are not trusted.
+// If the peer certificate is not verified by the TLS handshake, only the peer certificate itself is checked.
+func revocationCerts(info *ConnectionInfo) []*x509.Certificate {
+	if len(info.VerifiedChains) == 0 {
+		if len(info.PeerCertificates) == 0 {
+			return nil
+		}
+		return info.PeerCertificates[:1]
+	}
+	certs := []*x509.Certificate{}
+	seen := make(map[*x509.Certificate]bool)
+	for _, chain := range info.VerifiedChains {
+		for _, cert := range chain {
+			if !seen[cert] {
+				seen[cert] = true
+				certs = append(certs, cert)
+			}
+		}
+	}
+	return certs
+}
+
+func (c *Controller) release(key string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.conns[key]--
+	if c.conns[key] <= 0 {
+		delete(c.conns, key)
+	}
+}
+
+// remoteIP returns the IP address of the address. IPv4-mapped IPv6 addresses are returned as IPv4 addresses.
+func remoteIP(addr net.Addr) net.IP {
+	var ip net.IP
+	switch addr := addr.(type) {
+	case *net.UDPAddr:
+		ip = addr.IP
+	default:
+		host, _, err := net.SplitHostPort(addr.String())
+		if err != nil {
+			return nil
+		}
+		ip = net.ParseIP(host)
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4
+	}
+	return ip
+}
+
+func contains(networks []*net.IPNet, ip net.IP) bool {
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,188 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+
+	qpErr "github.com/quic-s/quics-protocol/pkg/error"
+)
+
+const (
+	// MethodToken is the method of the static token authentication.
+	MethodToken = "token"
+	// MethodHMAC is the method of the HMAC challenge-response authentication over a pre-shared key.
+	MethodHMAC = "hmac"
+)
+
+// Authenticator verifies the clients on the server side in the authentication phase of a connection.
+// The server sends a random challenge to the client, and the client responds to it with its Credential.
+// The binding is the keying material exported from the TLS session of the connection,
+// so a response computed from it cannot be replayed on another connection.
+type Authenticator interface {
+	// Method returns the name of the authentication method. It must be the same as the method of the Credential.
+	Method() string
+	// Verify verifies the response of the client and returns the authenticated principal.
+	Verify(identity string, challenge []byte, response []byte, binding []byte) (string, error)
+}
+
+// Credential responds to the challenge of the server on the client side in the authentication phase of a connection.
+type Credential interface {
+	// Method returns the name of the authentication method. It must be the same as the method of the Authenticator.
+	Method() string
+	// Identity returns the identity claimed by the client. It is sent before the challenge.
+	Identity() string
+	// Respond returns the response to the challenge bound to the TLS session by the binding.
+	Respond(challenge []byte, binding []byte) ([]byte, error)
+}
+
+// Sign returns the HMAC-SHA256 of the challenge and the binding with the key.
+// It is the response of the token and HMAC authentication.
+func Sign(key []byte, challenge []byte, binding []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(challenge)
+	mac.Write(binding)
+	return mac.Sum(nil)
+}
+
+type tokenAuthenticator struct {
+	tokens map[string]string
+}
+
+// NewTokenAuthenticator creates an authenticator of static tokens. The tokens are mapped to their principals.
+// The token itself is not sent, the client proves it knows the token by signing the challenge with it. (see Sign)
+func NewTokenAuthenticator(tokens map[string]string) Authenticator {
+	copied := make(map[string]string, len(tokens))
+	for token, principal := range tokens {
+		copied[token] = principal
+	}
+	return &tokenAuthenticator{tokens: copied}
+}
+
+func (a *tokenAuthenticator) Method() string {
+	return MethodToken
+}
+
+func (a *tokenAuthenticator) Verify(identity string, challenge []byte, response []byte, binding []byte) (string, error) {
+	// All tokens are checked, so the time taken does not tell which token matched.
+	principal, ok := "", false
+	for token, tokenPrincipal := range a.tokens {
+		if hmac.Equal(Sign([]byte(token), challenge, binding), response) && !ok {
+			principal, ok = tokenPrincipal, true
+		}
+	}
+	if !ok {
+		return "", qpErr.ErrAuthenticationFailed
+	}
+	return principal, nil
+}
+
+type tokenCredential struct {
+	token string
+}
+
+// NewTokenCredential creates a credential of a static token for NewTokenAuthenticator.
+func NewTokenCredential(token string) Credential {
+	return &tokenCredential{token: token}
+}
+
+func (c *tokenCredential) Method() string {
+	return MethodToken
+}
+
+func (c *tokenCredential) Identity() string {
+	return ""
+}
+
+func (c *tokenCredential) Respond(challenge []byte, binding []byte) ([]byte, error) {
+	return Sign([]byte(c.token), challenge, binding), nil
+}
+
+type hmacAuthenticator struct {
+	keys map[string][]byte
+}
+
+// NewHMACAuthenticator creates an authenticator of pre-shared keys. The keys are mapped to their identities,
+// and the identity of the client is the authenticated principal.
+func NewHMACAuthenticator(keys map[string][]byte) Authenticator {
+	copied := make(map[string][]byte, len(keys))
+	for identity, key := range keys {
+		copied[identity] = append([]byte{}, key...)
+	}
+	return &hmacAuthenticator{keys: copied}
+}
+
+func (a *hmacAuthenticator) Method() string {
+	return MethodHMAC
+}
+
+func (a *hmacAuthenticator) Verify(identity string, challenge []byte, response []byte, binding []byte) (string, error) {
+	key, ok := a.keys[identity]
+	if !ok || !hmac.Equal(Sign(key, challenge, binding), response) {
+		return "", qpErr.ErrAuthenticationFailed
+	}
+	return identity, nil
+}
+
+type hmacCredential struct {
+	identity string
+	key      []byte
+}
+
+// NewHMACCredential creates a credential of the identity and its pre-shared key for NewHMACAuthenticator.
+func NewHMACCredential(identity string, key []byte) Credential {
+	return &hmacCredential{identity: identity, key: key}
+}
+
+func (c *hmacCredential) Method() string {
+	return MethodHMAC
+}
+
+func (c *hmacCredential) Identity() string {
+	return c.identity
+}
+
+func (c *hmacCredential) Respond(challenge []byte, binding []byte) ([]byte, error) {
+	return Sign(c.key, challenge, binding), nil
+}
+
+type callbackAuthenticator struct {
+	method string
+	verify func(identity string, challenge []byte, response []byte, binding []byte) (string, error)
+}
+
+// NewCallbackAuthenticator creates an authenticator of a custom method verified by the callback function.
+// The callback must check that the response is bound to the challenge and the binding.
+func NewCallbackAuthenticator(method string, verify func(identity string, challenge []byte, response []byte, binding []byte) (string, error)) Authenticator {
+	return &callbackAuthenticator{method: method, verify: verify}
+}
+
+func (a *callbackAuthenticator) Method() string {
+	return a.method
+}
+
+func (a *callbackAuthenticator) Verify(identity string, challenge []byte, response []byte, binding []byte) (string, error) {
+	return a.verify(identity, challenge, response, binding)
+}
+
+type callbackCredential struct {
+	method   string
+	identity string
+	respond  func(challenge []byte, binding []byte) ([]byte, error)
+}
+
+// NewCallbackCredential creates a credential of a custom method responded by the callback function for NewCallbackAuthenticator.
+func NewCallbackCredential(method string, identity string, respond func(challenge []byte, binding []byte) ([]byte, error)) Credential {
+	return &callbackCredential{method: method, identity: identity, respond: respond}
+}
+
+func (c *callbackCredential) Method() string {
+	return c.method
+}
+
+func (c *callbackCredential) Identity() string {
+	return c.identity
+}
+
+func (c *callbackCredential) Respond(challenge []byte, binding []byte) ([]byte, error) {
+	return c.respond(challenge, binding)
+}
@@ -0,0 +1,191 @@
+package chunk
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"math/bits"
+)
+
+const (
+	// Default chunk sizes used by NewChunker.
+	DefaultMinSize = 16 * 1024
+	DefaultAvgSize = 64 * 1024
+	DefaultMaxSize = 256 * 1024
+)
+
+// Hash is the SHA-256 hash of a chunk. Chunks are addressed by this hash.
+type Hash [sha256.Size]byte
+
+// Sum returns the hash of the chunk data.
+func Sum(data []byte) Hash {
+	return sha256.Sum256(data)
+}
+
+// String returns the hash in hex format.
+func (h Hash) String() string {
+	return hex.EncodeToString(h[:])
+}
+
+// HashFromBytes converts bytes received through the connection to Hash.
+func HashFromBytes(b []byte) (Hash, error) {
+	var h Hash
+	if len(b) != len(h) {
+		return h, errors.New("invalid chunk hash size")
+	}
+	copy(h[:], b)
+	return h, nil
+}
+
+// Ref describes a chunk of a file.
+type Ref struct {
+	Hash   Hash
+	Offset int64
+	Size   int64
+}
+
+// Chunker splits a stream into content-defined chunks using FastCDC.
+// Since the cut points depend only on the content, identical regions of different files produce identical chunks
+// even if they are located at different offsets.
+type Chunker struct {
+	r       io.Reader
+	minSize int
+	avgSize int
+	maxSize int
+	maskS   uint64
+	maskL   uint64
+
+	buf    []byte
+	start  int
+	eof    bool
+	offset int64
+}
+
+// NewChunker creates a new chunker with the default chunk sizes.
+func NewChunker(r io.Reader) *Chunker {
+	c, _ := NewChunkerWithSize(r, DefaultMinSize, DefaultAvgSize, DefaultMaxSize)
+	return c
+}
+
+// NewChunkerWithSize creates a new chunker with the minimum, average and maximum chunk sizes.
+// The average size must be a power of two.
+func NewChunkerWithSize(r io.Reader, minSize int, avgSize int, maxSize int) (*Chunker, error) {
+	if minSize <= 0 || minSize > avgSize || avgSize > maxSize {
+		return nil, errors.New("chunk sizes must be 0 < min <= avg <= max")
+	}
+	if avgSize&(avgSize-1) != 0 {
+		return nil, errors.New("average chunk size must be a power of two")
+	}
+	avgBits := bits.TrailingZeros(uint(avgSize))
+	return &Chunker{
+		r:       r,
+		minSize: minSize,
+		avgSize: avgSize,
+		maxSize: maxSize,
+		// Normalized chunking: a harder mask before the average size and an easier one after it.
+		maskS: mask(avgBits + 1),
+		maskL: mask(avgBits - 1),
+		buf:   make([]byte, 0, 2*maxSize),
+	}, nil
+}
+
+// Next returns the next chunk and its data.
+// The data is only valid until the next call of Next.
+// It returns io.EOF when there are no more chunks.
+func (c *Chunker) Next() (Ref, []byte, error) {
+	if err := c.fill(); err != nil {
+		return Ref{}, nil, err
+	}
+	data := c.buf[c.start:]
+	if len(data) == 0 {
+		return Ref{}, nil, io.EOF
+	}
+
+	n := c.cut(data)
+	data = data[:n]
+	ref := Ref{
+		Hash:   Sum(data),
+		Offset: c.offset,
+		Size:   int64(n),
+	}
+	c.start += n
+	c.offset += int64(n)
+	return ref, data, nil
+}
+
+// fill reads from r until at least maxSize bytes are buffered or EOF is reached.
+func (c *Chunker) fill() error {
+	if c.eof || len(c.buf)-c.start >= c.maxSize {
+		return nil
+	}
+	n := copy(c.buf[:cap(c.buf)], c.buf[c.start:])
+	c.buf = c.buf[:n]
+	c.start = 0
+	for len(c.buf) < c.maxSize && !c.eof {
+		n, err := c.r.Read(c.buf[len(c.buf):cap(c.buf)])
+		c.buf = c.buf[:len(c.buf)+n]
+		if err == io.EOF {
+			c.eof = true
+		} else if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cut returns the length of the chunk at the beginning of data.
+func (c *Chunker) cut(data []byte) int {
+	n := len(data)
+	if n <= c.minSize {
+		return n
+	}
+	if n > c.maxSize {
+		n = c.maxSize
+	}
+	normal := c.avgSize
+	if normal > n {
+		normal = n
+	}
+
+	var fp uint64
+	i := c.minSize
+	for ; i < normal; i++ {
+		fp = (fp << 1) + gear[data[i]]
+		if fp&c.maskS == 0 {
+			return i + 1
+		}
+	}
+	for ; i < n; i++ {
+		fp = (fp << 1) + gear[data[i]]
+		if fp&c.maskL == 0 {
+			return i + 1
+		}
+	}
+	return n
+}
+
+// mask returns a mask with the given number of bits set in the upper part of the fingerprint,
+// which depends on the most recent bytes.
+func mask(n int) uint64 {
+	if n <= 0 {
+		return 0
+	}
+	return ((uint64(1) << n) - 1) << (64 - n)
+}
+
+// gear is the table of random values used by the rolling hash.
+// It is generated from a fixed seed, so every peer splits the same content into the same chunks.
+var gear [256]uint64
+
+func init() {
+	seed := uint64(0x71756963732d7170)
+	for i := range gear {
+		// splitmix64
+		seed += 0x9e3779b97f4a7c15
+		z := seed
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		gear[i] = z ^ (z >> 31)
+	}
+}
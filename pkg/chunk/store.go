@@ -0,0 +1,157 @@
+package chunk
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+var (
+	ErrChunkNotFound     = errors.New("chunk is not found in the store")
+	ErrChunkHashMismatch = errors.New("chunk data does not match the chunk hash")
+)
+
+// Store is a content-addressed chunk store.
+// The receiving side keeps chunks in the store and rebuilds files from it,
+// so a chunk shared by many files or many clients is stored only once.
+type Store interface {
+	// Has reports whether the chunk is in the store.
+	Has(hash Hash) (bool, error)
+	// Put stores the chunk data. The data must match the hash.
+	Put(hash Hash, data []byte) error
+	// Open opens the chunk data.
+	Open(hash Hash) (io.ReadCloser, error)
+}
+
+// DirStore is a Store that keeps each chunk as a file named by its hash in a directory.
+type DirStore struct {
+	dir string
+}
+
+// NewDirStore creates a new chunk store in the directory.
+// The directory is created if it does not exist.
+func NewDirStore(dir string) (*DirStore, error) {
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return nil, err
+	}
+	return &DirStore{
+		dir: dir,
+	}, nil
+}
+
+// Has reports whether the chunk is in the store.
+func (d *DirStore) Has(hash Hash) (bool, error) {
+	_, err := os.Stat(d.path(hash))
+	if err == nil {
+		return true, nil
+	}
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return false, err
+}
+
+// Put stores the chunk data. The data must match the hash.
+// The chunk file is written and synced to a temporary file first and renamed,
+// so a chunk in the store is always complete, even after a crash.
+func (d *DirStore) Put(hash Hash, data []byte) error {
+	if Sum(data) != hash {
+		return ErrChunkHashMismatch
+	}
+	path := d.path(hash)
+	err := os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".chunk-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	err = tmp.Sync()
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Open opens the chunk data.
+func (d *DirStore) Open(hash Hash) (io.ReadCloser, error) {
+	file, err := os.Open(d.path(hash))
+	if os.IsNotExist(err) {
+		return nil, ErrChunkNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// path returns the path of the chunk. Chunks are spread over subdirectories by the first byte of the hash.
+func (d *DirStore) path(hash Hash) string {
+	name := hash.String()
+	return filepath.Join(d.dir, name[:2], name)
+}
+
+// NewReader returns a reader that reads the chunks from the store in order.
+// Each chunk is opened only when it is read.
+func NewReader(store Store, hashes []Hash) io.ReadCloser {
+	return &reader{
+		store:  store,
+		hashes: hashes,
+	}
+}
+
+type reader struct {
+	store   Store
+	hashes  []Hash
+	current io.ReadCloser
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	for {
+		if r.current == nil {
+			if len(r.hashes) == 0 {
+				return 0, io.EOF
+			}
+			current, err := r.store.Open(r.hashes[0])
+			if err != nil {
+				return 0, err
+			}
+			r.current = current
+			r.hashes = r.hashes[1:]
+		}
+
+		n, err := r.current.Read(p)
+		if err == io.EOF {
+			r.current.Close()
+			r.current = nil
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+func (r *reader) Close() error {
+	if r.current != nil {
+		return r.current.Close()
+	}
+	return nil
+}
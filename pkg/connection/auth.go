@@ -0,0 +1,266 @@
+package connection
+
+import (
+	"crypto/rand"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quic-s/quics-protocol/pkg/auth"
+	qpErr "github.com/quic-s/quics-protocol/pkg/error"
+	qpStream "github.com/quic-s/quics-protocol/pkg/stream"
+	pb "github.com/quic-s/quics-protocol/proto/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// AuthTransactionName is the transaction name used for the authentication phase of a connection.
+	AuthTransactionName = ReservedTransactionPrefix + "auth"
+
+	// authBindingLabel is the label of the keying material exported from the TLS session to bind the authentication.
+	authBindingLabel = "EXPORTER-quics-protocol-auth"
+
+	authChallengeSize = 32
+	authBindingSize   = 32
+
+	// MaxPreAuthTransactions is the maximum number of reserved transactions of a connection handled at the same time
+	// before the peer is authenticated, like the authentication phase and enrollment.
+	MaxPreAuthTransactions = 4
+)
+
+// SetAuthenticators makes this connection require the authentication phase with one of the authenticators.
+// Until the peer is authenticated, the transactions of the peer are refused.
+// This method is used internally by quics-protocol.
+// So, you may don't need to use it directly.
+func (c *Connection) SetAuthenticators(authenticators []auth.Authenticator) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.authenticators = authenticators
+}
+
+// Principal returns the principal authenticated in the authentication phase of this connection.
+// On the server side, it is the principal of the client verified by the authenticator.
+// On the client side, it is the principal reported by the server.
+// It returns false if the connection is not authenticated.
+func (c *Connection) Principal() (string, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.principal, c.authenticated
+}
+
+// CheckAuthentication returns qpErr.ErrUnauthenticated if the transaction must be refused
+// because the connection requires the authentication phase and the peer is not authenticated yet.
+// The capabilities exchange, the control stream and enrollment are allowed before the authentication,
+// because the client opens the control stream right after the capabilities exchange.
+// Enrollment is authorized by its own bootstrap token.
+// This method is used internally by quics-protocol.
+// So, you may don't need to use it directly.
+func (c *Connection) CheckAuthentication(transactionName string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if len(c.authenticators) == 0 || c.authenticated {
+		return nil
+	}
+	switch transactionName {
+	case HelloTransactionName, ControlTransactionName, AuthTransactionName, EnrollTransactionName:
+		return nil
+	}
+	return qpErr.ErrUnauthenticated
+}
+
+// BeginPreAuth limits a reserved transaction handled before the peer is authenticated,
+// because reserved transactions are not limited by the concurrency limits.
+// If the connection requires the authentication phase and the peer is not authenticated yet,
+// the transaction is counted until done is called, and it must finish within the handshake timeout.
+// If the handshake timeout is not set, the default handshake timeout is used.
+// It returns a *qpErr.LimitError if MaxPreAuthTransactions are already handled.
+// This method is used internally by quics-protocol.
+// So, you may don't need to use it directly.
+func (c *Connection) BeginPreAuth(stream *qpStream.Stream) (done func(), err error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if len(c.authenticators) == 0 || c.authenticated {
+		return func() {}, nil
+	}
+	if c.preAuthTransactions >= MaxPreAuthTransactions {
+		return nil, &qpErr.LimitError{
+			Limit: "transactions before the authentication",
+			Value: int64(c.preAuthTransactions + 1),
+			Max:   MaxPreAuthTransactions,
+		}
+	}
+	timeout := c.timeouts.HandshakeTimeout
+	if timeout <= 0 {
+		timeout = qpStream.DefaultTimeouts.HandshakeTimeout
+	}
+	err = stream.Stream.SetDeadline(time.Now().Add(timeout))
+	if err != nil {
+		return nil, err
+	}
+	c.preAuthTransactions++
+	return func() {
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+		c.preAuthTransactions--
+	}, nil
+}
+
+// Authenticate runs the authentication phase with the credential on the client side.
+// The server sends a challenge, and the response of the credential is bound to the TLS session of this connection.
+// It must be called before opening any other transaction to a server that requires authentication.
+func (c *Connection) Authenticate(credential auth.Credential) error {
+	return c.OpenTransaction(AuthTransactionName, func(stream *qpStream.Stream, transactionName string, transactionID []byte) error {
+		err := writeAuthentication(stream, &pb.Authentication{
+			Method:   credential.Method(),
+			Identity: credential.Identity(),
+		})
+		if err != nil {
+			return err
+		}
+		challenge, err := readAuthentication(stream)
+		if err != nil {
+			return err
+		}
+		if len(challenge.Challenge) != authChallengeSize {
+			return errors.New("invalid authentication challenge")
+		}
+
+		binding, err := c.authBinding()
+		if err != nil {
+			return err
+		}
+		response, err := credential.Respond(challenge.Challenge, binding)
+		if err != nil {
+			return err
+		}
+		err = writeAuthentication(stream, &pb.Authentication{
+			Response: response,
+		})
+		if err != nil {
+			return err
+		}
+		result, err := readAuthentication(stream)
+		if err != nil {
+			return err
+		}
+
+		c.mutex.Lock()
+		c.principal = result.Principal
+		c.authenticated = true
+		c.mutex.Unlock()
+		return nil
+	})
+}
+
+// RecvAuthentication runs the authentication phase on the server side.
+// If the authentication fails, the connection is closed with qpErr.AuthenticationFailedCode.
+// This method is used internally to handle transactions named AuthTransactionName.
+// So, you may don't need to use it directly.
+func (c *Connection) RecvAuthentication(stream *qpStream.Stream) error {
+	principal, err := c.recvAuthentication(stream)
+	if err != nil {
+		requestId, uuidErr := uuid.New().MarshalBinary()
+		if uuidErr == nil {
+			qpStream.WriteHeader(stream, pb.RequestType_AUTH, requestId, qpErr.ErrAuthenticationFailed.Error())
+		}
+		c.Metrics().Error(qpErr.AuthenticationFailedCode)
+		c.Conn.CloseWithError(qpErr.AuthenticationFailedCode, qpErr.ErrAuthenticationFailed.Error())
+		return err
+	}
+
+	c.mutex.Lock()
+	c.principal = principal
+	c.authenticated = true
+	c.mutex.Unlock()
+	return writeAuthentication(stream, &pb.Authentication{
+		Principal: principal,
+	})
+}
+
+func (c *Connection) recvAuthentication(stream *qpStream.Stream) (string, error) {
+	request, err := readAuthentication(stream)
+	if err != nil {
+		return "", err
+	}
+
+	c.mutex.Lock()
+	authenticated := c.authenticated
+	var authenticator auth.Authenticator
+	for _, candidate := range c.authenticators {
+		if candidate.Method() == request.Method {
+			authenticator = candidate
+			break
+		}
+	}
+	c.mutex.Unlock()
+	if authenticated {
+		return "", errors.New("connection is already authenticated")
+	}
+	if authenticator == nil {
+		return "", errors.New("authentication method is not supported: " + request.Method)
+	}
+
+	challenge := make([]byte, authChallengeSize)
+	_, err = rand.Read(challenge)
+	if err != nil {
+		return "", err
+	}
+	err = writeAuthentication(stream, &pb.Authentication{
+		Challenge: challenge,
+	})
+	if err != nil {
+		return "", err
+	}
+	response, err := readAuthentication(stream)
+	if err != nil {
+		return "", err
+	}
+
+	binding, err := c.authBinding()
+	if err != nil {
+		return "", err
+	}
+	return authenticator.Verify(request.Identity, challenge, response.Response, binding)
+}
+
+// authBinding exports the keying material of the TLS session, which is the same on both sides of this connection only.
+func (c *Connection) authBinding() ([]byte, error) {
+	state := c.Conn.ConnectionState().TLS
+	return state.ExportKeyingMaterial(authBindingLabel, nil, authBindingSize)
+}
+
+func writeAuthentication(stream *qpStream.Stream, authentication *pb.Authentication) error {
+	requestId, err := uuid.New().MarshalBinary()
+	if err != nil {
+		return err
+	}
+	err = qpStream.WriteHeader(stream, pb.RequestType_AUTH, requestId, "")
+	if err != nil {
+		return err
+	}
+	authenticationOut, err := proto.Marshal(authentication)
+	if err != nil {
+		return err
+	}
+	return qpStream.WriteMessage(stream, authenticationOut)
+}
+
+func readAuthentication(stream *qpStream.Stream) (*pb.Authentication, error) {
+	header, err := qpStream.ReadHeader(stream)
+	if err != nil {
+		return nil, err
+	}
+	if header.RequestType != pb.RequestType_AUTH {
+		return nil, errors.New("request type is not Auth")
+	}
+	authenticationBuf, err := qpStream.ReadMessage(stream)
+	if err != nil {
+		return nil, err
+	}
+	authentication := &pb.Authentication{}
+	err = proto.Unmarshal(authenticationBuf, authentication)
+	if err != nil {
+		return nil, err
+	}
+	return authentication, nil
+}
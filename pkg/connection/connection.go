@@ -3,17 +3,65 @@ package connection
 import (
 	"context"
 	"errors"
+	"log"
+	"net"
+	"sync"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/quic-go/quic-go"
+	"github.com/quic-s/quics-protocol/pkg/auth"
+	"github.com/quic-s/quics-protocol/pkg/enrollment"
+	qpErr "github.com/quic-s/quics-protocol/pkg/error"
+	"github.com/quic-s/quics-protocol/pkg/metrics"
+	"github.com/quic-s/quics-protocol/pkg/ratelimit"
 	qpStream "github.com/quic-s/quics-protocol/pkg/stream"
+	qpTls "github.com/quic-s/quics-protocol/pkg/tls"
 	pb "github.com/quic-s/quics-protocol/proto/v1"
 )
 
 // Connection is a connection instance that is created when a client connects to a server.
 type Connection struct {
-	logLevel int
-	Conn     quic.Connection
+	logLevel        int
+	Conn            quic.Connection
+	uploadLimiter   *ratelimit.Limiter
+	downloadLimiter *ratelimit.Limiter
+
+	mutex     sync.Mutex
+	transfers map[string]*parallelTransfer
+	limits    qpStream.Limits
+	timeouts  qpStream.Timeouts
+	timedOut  int
+
+	authenticators      []auth.Authenticator
+	authenticated       bool
+	principal           string
+	enrollment          *enrollment.Enrollment
+	preAuthTransactions int
+
+	capabilities     Capabilities
+	peerCapabilities *Capabilities
+
+	control          *qpStream.Stream
+	controlMutex     sync.Mutex
+	pingSequence     uint64
+	pings            map[uint64]chan time.Duration
+	rtt              time.Duration
+	peerSettings     map[string]string
+	settingsFunc     func(settings map[string]string)
+	goAway           *GoAway
+	goAwayFunc       func(goAway GoAway)
+	openTransactions int
+	drained          chan struct{}
+	receivingRanges  chan struct{}
+
+	created               time.Time
+	counters              *qpStream.Counters
+	completedTransactions int64
+	smoothedRTT           time.Duration
+	minRTT                time.Duration
+
+	metrics metrics.Metrics
 }
 
 // New creates a new connection instance.
@@ -28,11 +76,169 @@ func New(logLevel int, conn quic.Connection) (*Connection, error) {
 	}
 
 	return &Connection{
-		logLevel: logLevel,
-		Conn:     conn,
+		logLevel:        logLevel,
+		Conn:            conn,
+		uploadLimiter:   ratelimit.New(0, nil),
+		downloadLimiter: ratelimit.New(0, nil),
+		transfers:       make(map[string]*parallelTransfer),
+		limits:          qpStream.DefaultLimits,
+		timeouts:        qpStream.DefaultTimeouts,
+		capabilities:    DefaultCapabilities,
+		pings:           make(map[uint64]chan time.Duration),
+		peerSettings:    make(map[string]string),
+		created:         time.Now(),
+		counters:        &qpStream.Counters{},
+		metrics:         metrics.Noop{},
 	}, nil
 }
 
+// SetParentLimiters sets the upload and download limiters of the quics-protocol instance as parents of the limiters of the connection.
+// This method is used internally by quics-protocol.
+// So, you may don't need to use it directly.
+func (c *Connection) SetParentLimiters(uploadLimiter *ratelimit.Limiter, downloadLimiter *ratelimit.Limiter) {
+	c.uploadLimiter.SetParent(uploadLimiter)
+	c.downloadLimiter.SetParent(downloadLimiter)
+}
+
+// NewStream creates a new stream instance of a transaction of this connection.
+// This method is used internally by quics-protocol.
+// So, you may don't need to use it directly.
+func (c *Connection) NewStream(stream quic.Stream) (*qpStream.Stream, error) {
+	newStream, err := qpStream.New(c.logLevel, stream)
+	if err != nil {
+		return nil, err
+	}
+	newStream.SetParentLimiters(c.uploadLimiter, c.downloadLimiter)
+	newStream.SetContext(c.Conn.Context())
+	// Sparse files are supported by all peers negotiating a protocol version.
+	newStream.SetSparseSupported(c.Version() >= 1)
+	newStream.SetLimits(c.Limits())
+	if peer, ok := c.PeerCapabilities(); ok {
+		newStream.SetMaxSendMessageSize(peer.MaxMessageSize)
+	}
+	newStream.SetIdleTimeout(c.Timeouts().IdleTimeout)
+	newStream.SetTimeoutFunc(func() {
+		c.Metrics().Error(qpErr.IdleTimeoutCode)
+		c.streamTimedOut()
+	})
+	newStream.SetCounters(c.counters)
+	newStream.SetMetrics(c.Metrics())
+	return newStream, nil
+}
+
+// SetMetrics sets the metrics that this connection and its transactions report to.
+// This method is used internally by quics-protocol.
+// So, you may don't need to use it directly.
+func (c *Connection) SetMetrics(m metrics.Metrics) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.metrics = m
+}
+
+// Metrics returns the metrics that this connection and its transactions report to.
+func (c *Connection) Metrics() metrics.Metrics {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.metrics
+}
+
+// SetLimits sets the limits on the data received in the transactions of this connection.
+// The limits are applied to the transactions opened after it is called.
+// Each transaction can have its own limits too. (see Stream.SetLimits)
+func (c *Connection) SetLimits(limits qpStream.Limits) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.limits = limits
+}
+
+// Limits returns the limits on the data received in the transactions of this connection.
+func (c *Connection) Limits() qpStream.Limits {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.limits
+}
+
+// SetUploadLimit limits the rate of sending data of all transactions of this connection in bytes per second.
+// The limit of the quics-protocol instance is applied together.
+// The limit can be changed at any time. A limit of 0 or less means unlimited.
+func (c *Connection) SetUploadLimit(bytesPerSecond int64) {
+	c.uploadLimiter.SetLimit(bytesPerSecond)
+}
+
+// SetDownloadLimit limits the rate of receiving data of all transactions of this connection in bytes per second.
+// The limit of the quics-protocol instance is applied together.
+// The limit can be changed at any time. A limit of 0 or less means unlimited.
+func (c *Connection) SetDownloadLimit(bytesPerSecond int64) {
+	c.downloadLimiter.SetLimit(bytesPerSecond)
+}
+
+// SetTimeouts sets the timeouts of the transactions of this connection.
+// The timeouts are applied to the transactions opened after it is called.
+// The idle timeout of each transaction can be changed too. (see Stream.SetIdleTimeout)
+func (c *Connection) SetTimeouts(timeouts qpStream.Timeouts) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.timeouts = timeouts
+}
+
+// Timeouts returns the timeouts of the transactions of this connection.
+func (c *Connection) Timeouts() qpStream.Timeouts {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.timeouts
+}
+
+// streamTimedOut counts a timed out transaction,
+// and closes the connection with qpErr.TooManyTimeoutsCode when there are too many of them.
+func (c *Connection) streamTimedOut() {
+	c.mutex.Lock()
+	c.timedOut++
+	tooMany := c.timeouts.MaxTimeoutsPerConnection > 0 && c.timedOut >= c.timeouts.MaxTimeoutsPerConnection
+	c.mutex.Unlock()
+	if tooMany {
+		log.Println("quics-protocol: ", "close connection with too many timed out transactions", c.Conn.RemoteAddr())
+		c.Metrics().Error(qpErr.TooManyTimeoutsCode)
+		c.Conn.CloseWithError(qpErr.TooManyTimeoutsCode, "too many timed out transactions")
+	}
+}
+
+// AcceptTransaction receives the transaction handshake of an accepted stream within the handshake timeout.
+// If the handshake is not received in time, the stream is reset with qpErr.HandshakeTimeoutCode
+// and qpErr.ErrHandshakeTimeout is returned.
+// This method is used internally by quics-protocol.
+// So, you may don't need to use it directly.
+func (c *Connection) AcceptTransaction(stream *qpStream.Stream) (*pb.Transaction, error) {
+	timeout := c.Timeouts().HandshakeTimeout
+	if timeout > 0 {
+		stream.Stream.SetReadDeadline(time.Now().Add(timeout))
+	}
+	transaction, err := RecvTransactionHandshake(stream)
+	netErr := net.Error(nil)
+	if timeout > 0 && errors.As(err, &netErr) && netErr.Timeout() {
+		c.Metrics().Error(qpErr.HandshakeTimeoutCode)
+		stream.Stream.CancelRead(qpErr.HandshakeTimeoutCode)
+		stream.Stream.CancelWrite(qpErr.HandshakeTimeoutCode)
+		c.streamTimedOut()
+		return nil, qpErr.ErrHandshakeTimeout
+	}
+	if err != nil {
+		return nil, err
+	}
+	stream.Stream.SetReadDeadline(time.Time{})
+	return transaction, nil
+}
+
+// PeerIdentity returns the identity parsed from the certificate of the peer.
+// It returns nil if the peer did not send a certificate.
+// The certificate is verified during the TLS handshake only if the server requires client certificates. (see QP.SetClientCAs)
+func (c *Connection) PeerIdentity() *qpTls.Identity {
+	certs := c.Conn.ConnectionState().TLS.PeerCertificates
+	if len(certs) == 0 {
+		return nil
+	}
+	return qpTls.NewIdentity(certs[0])
+}
+
 // Close closes the connection.
 func (c *Connection) Close() error {
 	if c == nil || c.Conn == nil {
@@ -58,6 +264,7 @@ func (c *Connection) CloseWithError(message string) error {
 }
 
 // OpenTransaction opens a transaction to the server.
+// After the peer sent a GOAWAY signal, it returns qpErr.ErrGoingAway instead of opening a transaction.
 // The transaction name and transaction function are needed as parameters.
 // The transaction name is used to determine which handler to use on the receiving side.
 // `transactionFunc“ is called when the transaction is opened.
@@ -67,12 +274,16 @@ func (c *Connection) OpenTransaction(transactionName string, transactionFunc fun
 	if c == nil || c.Conn == nil {
 		return errors.New("connection instance is nil")
 	}
+	if _, ok := c.GoingAway(); ok {
+		return qpErr.ErrGoingAway
+	}
+	defer c.TrackTransaction(transactionName)()
 
 	stream, err := c.Conn.OpenStreamSync(context.Background())
 	if err != nil {
 		return err
 	}
-	newStream, err := qpStream.New(c.logLevel, stream)
+	newStream, err := c.NewStream(stream)
 	if err != nil {
 		newStream.SendError(err.Error())
 		return err
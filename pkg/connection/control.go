@@ -0,0 +1,425 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quic-go/quic-go"
+	qpErr "github.com/quic-s/quics-protocol/pkg/error"
+	qpStream "github.com/quic-s/quics-protocol/pkg/stream"
+	pb "github.com/quic-s/quics-protocol/proto/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// ControlTransactionName is the transaction name of the control stream opened when a connection starts.
+// The control stream stays open while the connection is alive, and carries the connection-level signals.
+const ControlTransactionName = ReservedTransactionPrefix + "control"
+
+const (
+	// MaxSettings is the maximum number of settings received from the peer.
+	MaxSettings = 64
+	// MaxSettingLength is the maximum length of the key and of the value of a setting received from the peer in bytes.
+	MaxSettingLength = 1024
+
+	// maxControlMessageSize is the maximum size of a signal received on the control stream, which fits MaxSettings settings.
+	maxControlMessageSize = 256 << 10
+	// controlCallbackQueue is the number of the callbacks of the signals waiting to be called.
+	// When the queue is full, the control stream is not read until a callback returns.
+	controlCallbackQueue = 16
+)
+
+// GoAway is a GOAWAY signal telling the peer to stop opening transactions and reconnect.
+type GoAway struct {
+	// Address is the address to reconnect to, like "host:port". It is empty to reconnect to the same address.
+	Address string
+	// Reason is the reason of the GOAWAY for logging.
+	Reason string
+}
+
+// OpenControl opens the control stream to the server on the client side,
+// and handles the signals of the server in a new goroutine until the connection is closed.
+// This method is used internally when dialing.
+// So, you may don't need to use it directly.
+func (c *Connection) OpenControl() error {
+	stream, err := c.Conn.OpenStreamSync(context.Background())
+	if err != nil {
+		return err
+	}
+	newStream, err := c.NewStream(stream)
+	if err != nil {
+		return err
+	}
+	newStream.SetIdleTimeout(0)
+	limits := newStream.Limits()
+	limits.MaxMessageSize = maxControlMessageSize
+	limits.MaxRequestsPerTransaction = 0
+	newStream.SetLimits(limits)
+
+	transactionID, err := uuid.New().MarshalBinary()
+	if err != nil {
+		newStream.Close()
+		return err
+	}
+	err = TransactionHandshake(newStream, ControlTransactionName, transactionID)
+	if err != nil {
+		newStream.Close()
+		return err
+	}
+
+	c.mutex.Lock()
+	c.control = newStream
+	c.mutex.Unlock()
+	go func() {
+		defer newStream.Close()
+		err := c.handleControl(newStream)
+		if err != nil {
+			log.Println("quics-protocol: ", err)
+		}
+	}()
+	return nil
+}
+
+// RecvControl handles the signals of the client on the control stream on the server side until the connection is closed.
+// This method is used internally to handle transactions named ControlTransactionName.
+// So, you may don't need to use it directly.
+func (c *Connection) RecvControl(stream *qpStream.Stream) error {
+	stream.SetIdleTimeout(0)
+	limits := stream.Limits()
+	limits.MaxMessageSize = maxControlMessageSize
+	limits.MaxRequestsPerTransaction = 0
+	stream.SetLimits(limits)
+
+	c.mutex.Lock()
+	if c.control != nil {
+		c.mutex.Unlock()
+		return errors.New("control stream is already opened")
+	}
+	c.control = stream
+	c.mutex.Unlock()
+	return c.handleControl(stream)
+}
+
+// handleControl reads the signals of the peer on the control stream until the connection is closed.
+// Pings are answered with pongs, and the callbacks of settings and GOAWAY are called in order in another goroutine.
+// Before the peer is authenticated, only pings and pongs are accepted.
+func (c *Connection) handleControl(stream *qpStream.Stream) error {
+	callbacks := make(chan func(), controlCallbackQueue)
+	defer close(callbacks)
+	go func() {
+		for callback := range callbacks {
+			callback()
+		}
+	}()
+
+	for {
+		control, err := readControl(stream)
+		if err != nil {
+			if c.Conn.Context().Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		switch control.Type {
+		case pb.ControlType_PING:
+			err = c.writeControl(&pb.Control{
+				Type:      pb.ControlType_PONG,
+				Sequence:  control.Sequence,
+				Timestamp: control.Timestamp,
+			})
+			if err != nil {
+				return err
+			}
+		case pb.ControlType_PONG:
+			rtt := time.Since(time.Unix(0, control.Timestamp))
+			c.mutex.Lock()
+			pong := c.pings[control.Sequence]
+			delete(c.pings, control.Sequence)
+			c.mutex.Unlock()
+			if pong != nil {
+				pong <- rtt
+			}
+		case pb.ControlType_SETTINGS:
+			err = c.checkAuthenticatedControl(stream)
+			if err != nil {
+				return err
+			}
+			c.mutex.Lock()
+			err = checkSettings(c.peerSettings, control.Settings)
+			if err != nil {
+				c.mutex.Unlock()
+				c.resetControl(stream, qpErr.LimitExceededCode)
+				return err
+			}
+			for key, value := range control.Settings {
+				c.peerSettings[key] = value
+			}
+			settingsFunc := c.settingsFunc
+			c.mutex.Unlock()
+			if settingsFunc != nil {
+				settings := control.Settings
+				callbacks <- func() { settingsFunc(settings) }
+			}
+		case pb.ControlType_GOAWAY:
+			err = c.checkAuthenticatedControl(stream)
+			if err != nil {
+				return err
+			}
+			goAway := GoAway{
+				Address: control.Address,
+				Reason:  control.Reason,
+			}
+			c.mutex.Lock()
+			c.goAway = &goAway
+			goAwayFunc := c.goAwayFunc
+			c.mutex.Unlock()
+			if goAwayFunc != nil {
+				callbacks <- func() { goAwayFunc(goAway) }
+			}
+		}
+	}
+}
+
+// checkAuthenticatedControl resets the control stream with qpErr.UnauthenticatedCode
+// if the connection requires the authentication phase and the peer is not authenticated yet.
+func (c *Connection) checkAuthenticatedControl(stream *qpStream.Stream) error {
+	c.mutex.Lock()
+	authenticated := len(c.authenticators) == 0 || c.authenticated
+	c.mutex.Unlock()
+	if authenticated {
+		return nil
+	}
+	c.resetControl(stream, qpErr.UnauthenticatedCode)
+	return qpErr.ErrUnauthenticated
+}
+
+// checkSettings returns a *qpErr.LimitError if the settings received exceed MaxSettingLength,
+// or the settings merged into the settings received before exceed MaxSettings.
+func checkSettings(peerSettings map[string]string, settings map[string]string) error {
+	count := len(peerSettings)
+	for key, value := range settings {
+		length := len(key)
+		if len(value) > length {
+			length = len(value)
+		}
+		if length > MaxSettingLength {
+			return &qpErr.LimitError{
+				Limit: "setting length",
+				Value: int64(length),
+				Max:   MaxSettingLength,
+			}
+		}
+		if _, ok := peerSettings[key]; !ok {
+			count++
+		}
+	}
+	if count > MaxSettings {
+		return &qpErr.LimitError{
+			Limit: "settings",
+			Value: int64(count),
+			Max:   MaxSettings,
+		}
+	}
+	return nil
+}
+
+// resetControl resets the control stream with the error code.
+func (c *Connection) resetControl(stream *qpStream.Stream, code uint64) {
+	c.Metrics().Error(code)
+	stream.Stream.CancelRead(quic.StreamErrorCode(code))
+	stream.Stream.CancelWrite(quic.StreamErrorCode(code))
+}
+
+// Ping sends a ping on the control stream and waits for its pong.
+// It returns the round-trip time of the ping.
+func (c *Connection) Ping(ctx context.Context) (time.Duration, error) {
+	pong := make(chan time.Duration, 1)
+	c.mutex.Lock()
+	c.pingSequence++
+	sequence := c.pingSequence
+	c.pings[sequence] = pong
+	c.mutex.Unlock()
+	defer func() {
+		c.mutex.Lock()
+		delete(c.pings, sequence)
+		c.mutex.Unlock()
+	}()
+
+	err := c.writeControl(&pb.Control{
+		Type:      pb.ControlType_PING,
+		Sequence:  sequence,
+		Timestamp: time.Now().UnixNano(),
+	})
+	if err != nil {
+		return 0, err
+	}
+	select {
+	case rtt := <-pong:
+		c.recordRTT(rtt)
+		return rtt, nil
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	case <-c.Conn.Context().Done():
+		return 0, c.Conn.Context().Err()
+	}
+}
+
+// RTT returns the round-trip time measured by the last ping. (see Ping)
+// It returns 0 if no ping is answered yet.
+func (c *Connection) RTT() time.Duration {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.rtt
+}
+
+// UpdateSettings sends the settings to the peer on the control stream.
+// The peer merges them into the settings received before. (see PeerSettings)
+func (c *Connection) UpdateSettings(settings map[string]string) error {
+	return c.writeControl(&pb.Control{
+		Type:     pb.ControlType_SETTINGS,
+		Settings: settings,
+	})
+}
+
+// PeerSettings returns a copy of the settings received from the peer.
+func (c *Connection) PeerSettings() map[string]string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	settings := make(map[string]string, len(c.peerSettings))
+	for key, value := range c.peerSettings {
+		settings[key] = value
+	}
+	return settings
+}
+
+// SetSettingsFunc sets the callback function called with the settings received from the peer.
+func (c *Connection) SetSettingsFunc(settingsFunc func(settings map[string]string)) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.settingsFunc = settingsFunc
+}
+
+// GoAway sends a GOAWAY signal to the peer on the control stream.
+// The peer stops opening transactions on this connection, finishes the open ones and reconnects to the address.
+// If the address is empty, the peer reconnects to the same address.
+func (c *Connection) GoAway(address string, reason string) error {
+	return c.writeControl(&pb.Control{
+		Type:    pb.ControlType_GOAWAY,
+		Address: address,
+		Reason:  reason,
+	})
+}
+
+// GoingAway returns the GOAWAY signal received from the peer.
+// It returns false if the peer did not send a GOAWAY signal.
+func (c *Connection) GoingAway() (GoAway, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.goAway == nil {
+		return GoAway{}, false
+	}
+	return *c.goAway, true
+}
+
+// SetGoAwayFunc sets the callback function called when a GOAWAY signal is received from the peer.
+// This method is used internally by quics-protocol to reconnect the clients.
+// So, you may don't need to use it directly.
+func (c *Connection) SetGoAwayFunc(goAwayFunc func(goAway GoAway)) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.goAwayFunc = goAwayFunc
+}
+
+// TrackTransaction counts a transaction that is opened or handled on this connection until done is called,
+// so Drain can wait for it. Reserved transactions are not counted.
+// This method is used internally by quics-protocol.
+// So, you may don't need to use it directly.
+func (c *Connection) TrackTransaction(transactionName string) (done func()) {
+	if strings.HasPrefix(transactionName, ReservedTransactionPrefix) {
+		return func() {}
+	}
+	c.mutex.Lock()
+	c.openTransactions++
+	c.mutex.Unlock()
+	return func() {
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+		c.openTransactions--
+		c.completedTransactions++
+		if c.openTransactions == 0 && c.drained != nil {
+			close(c.drained)
+			c.drained = nil
+		}
+	}
+}
+
+// Drain waits until the transactions opened and handled on this connection are finished or the context is done.
+func (c *Connection) Drain(ctx context.Context) error {
+	c.mutex.Lock()
+	if c.openTransactions == 0 {
+		c.mutex.Unlock()
+		return nil
+	}
+	if c.drained == nil {
+		c.drained = make(chan struct{})
+	}
+	drained := c.drained
+	c.mutex.Unlock()
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// writeControl writes a signal on the control stream.
+// The writes of the signals are serialized, so they can be sent from multiple goroutines.
+func (c *Connection) writeControl(control *pb.Control) error {
+	c.mutex.Lock()
+	stream := c.control
+	c.mutex.Unlock()
+	if stream == nil {
+		return qpErr.ErrNoControlStream
+	}
+
+	c.controlMutex.Lock()
+	defer c.controlMutex.Unlock()
+	requestId, err := uuid.New().MarshalBinary()
+	if err != nil {
+		return err
+	}
+	err = qpStream.WriteHeader(stream, pb.RequestType_CONTROL, requestId, "")
+	if err != nil {
+		return err
+	}
+	controlOut, err := proto.Marshal(control)
+	if err != nil {
+		return err
+	}
+	return qpStream.WriteMessage(stream, controlOut)
+}
+
+func readControl(stream *qpStream.Stream) (*pb.Control, error) {
+	header, err := qpStream.ReadHeader(stream)
+	if err != nil {
+		return nil, err
+	}
+	if header.RequestType != pb.RequestType_CONTROL {
+		return nil, errors.New("request type is not Control")
+	}
+	controlBuf, err := qpStream.ReadMessage(stream)
+	if err != nil {
+		return nil, err
+	}
+	control := &pb.Control{}
+	err = proto.Unmarshal(controlBuf, control)
+	if err != nil {
+		return nil, err
+	}
+	return control, nil
+}
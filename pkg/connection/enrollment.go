@@ -0,0 +1,185 @@
+package connection
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"net"
+
+	"github.com/google/uuid"
+	"github.com/quic-s/quics-protocol/pkg/enrollment"
+	qpStream "github.com/quic-s/quics-protocol/pkg/stream"
+	qpTls "github.com/quic-s/quics-protocol/pkg/tls"
+	pb "github.com/quic-s/quics-protocol/proto/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// EnrollTransactionName is the transaction name used to request a client certificate.
+const EnrollTransactionName = ReservedTransactionPrefix + "enroll"
+
+// SetEnrollment makes this connection issue client certificates with the enrollment.
+// This method is used internally by quics-protocol.
+// So, you may don't need to use it directly.
+func (c *Connection) SetEnrollment(enrollment *enrollment.Enrollment) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.enrollment = enrollment
+}
+
+// Enroll requests a client certificate from the server on the client side.
+// A new key of the key type of the options is generated, and a certificate signing request (CSR)
+// with the common name and hosts of the options is sent with the bootstrap token.
+// To renew a certificate, use an empty token over a connection with the current certificate.
+// The issued certificate is returned with the new key. Use WriteCertificate to store it.
+func (c *Connection) Enroll(token string, opts qpTls.CertificateOptions) (*tls.Certificate, error) {
+	key, err := qpTls.GenerateKey(opts.KeyType)
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: opts.CommonName},
+	}
+	for _, host := range opts.Hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, template, key)
+	if err != nil {
+		return nil, err
+	}
+
+	var cert *tls.Certificate
+	err = c.OpenTransaction(EnrollTransactionName, func(stream *qpStream.Stream, transactionName string, transactionID []byte) error {
+		err := writeEnrollment(stream, &pb.Enrollment{
+			Token: token,
+			Csr:   csr,
+		})
+		if err != nil {
+			return err
+		}
+		issued, err := readEnrollment(stream)
+		if err != nil {
+			return err
+		}
+		if len(issued.Certificates) == 0 {
+			return qpTls.ErrNoCertificate
+		}
+		leaf, err := x509.ParseCertificate(issued.Certificates[0])
+		if err != nil {
+			return err
+		}
+		publicKey, err := x509.MarshalPKIXPublicKey(key.Public())
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(leaf.RawSubjectPublicKeyInfo, publicKey) {
+			return errors.New("issued certificate does not match the key")
+		}
+		cert = &tls.Certificate{
+			Certificate: issued.Certificates,
+			PrivateKey:  key,
+			Leaf:        leaf,
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cert, nil
+}
+
+// RecvEnrollment receives a certificate signing request and sends the certificate issued for it on the server side.
+// This method is used internally to handle transactions named EnrollTransactionName.
+// So, you may don't need to use it directly.
+func (c *Connection) RecvEnrollment(stream *qpStream.Stream) error {
+	certificates, err := c.recvEnrollment(stream)
+	if err != nil {
+		requestId, uuidErr := uuid.New().MarshalBinary()
+		if uuidErr == nil {
+			qpStream.WriteHeader(stream, pb.RequestType_ENROLL, requestId, "enrollment is refused: "+err.Error())
+		}
+		return err
+	}
+	return writeEnrollment(stream, &pb.Enrollment{
+		Certificates: certificates,
+	})
+}
+
+func (c *Connection) recvEnrollment(stream *qpStream.Stream) ([][]byte, error) {
+	request, err := readEnrollment(stream)
+	if err != nil {
+		return nil, err
+	}
+	c.mutex.Lock()
+	enroll := c.enrollment
+	c.mutex.Unlock()
+	if enroll == nil || enroll.CA == nil || enroll.Approve == nil {
+		return nil, errors.New("enrollment is not enabled")
+	}
+
+	csr, err := x509.ParseCertificateRequest(request.Csr)
+	if err != nil {
+		return nil, err
+	}
+	err = csr.CheckSignature()
+	if err != nil {
+		return nil, err
+	}
+	opts, err := enroll.Approve(&enrollment.Request{
+		Token:              request.Token,
+		CertificateRequest: csr,
+		Peer:               c.PeerIdentity(),
+		RemoteAddr:         c.Conn.RemoteAddr(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	certDER, err := enroll.CA.Sign(opts, csr.PublicKey, []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth})
+	if err != nil {
+		return nil, err
+	}
+	return append([][]byte{certDER}, enroll.CA.Chain()...), nil
+}
+
+func writeEnrollment(stream *qpStream.Stream, enrollment *pb.Enrollment) error {
+	requestId, err := uuid.New().MarshalBinary()
+	if err != nil {
+		return err
+	}
+	err = qpStream.WriteHeader(stream, pb.RequestType_ENROLL, requestId, "")
+	if err != nil {
+		return err
+	}
+	enrollmentOut, err := proto.Marshal(enrollment)
+	if err != nil {
+		return err
+	}
+	return qpStream.WriteMessage(stream, enrollmentOut)
+}
+
+func readEnrollment(stream *qpStream.Stream) (*pb.Enrollment, error) {
+	header, err := qpStream.ReadHeader(stream)
+	if err != nil {
+		return nil, err
+	}
+	if header.RequestType != pb.RequestType_ENROLL {
+		return nil, errors.New("request type is not Enroll")
+	}
+	enrollmentBuf, err := qpStream.ReadMessage(stream)
+	if err != nil {
+		return nil, err
+	}
+	enrollment := &pb.Enrollment{}
+	err = proto.Unmarshal(enrollmentBuf, enrollment)
+	if err != nil {
+		return nil, err
+	}
+	return enrollment, nil
+}
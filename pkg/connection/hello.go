@@ -0,0 +1,234 @@
+package connection
+
+import (
+	"crypto/tls"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	qpStream "github.com/quic-s/quics-protocol/pkg/stream"
+	pb "github.com/quic-s/quics-protocol/proto/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// ProtocolVersion is the current version of the protocol.
+	ProtocolVersion = 1
+
+	// ALPNPrefix is the prefix of the versioned ALPN identifiers, like "quics/1".
+	ALPNPrefix = "quics/"
+
+	// HelloTransactionName is the transaction name used to exchange the capabilities when a connection starts.
+	HelloTransactionName = ReservedTransactionPrefix + "hello"
+)
+
+// ALPN returns the ALPN identifier of the protocol version.
+func ALPN(version int) string {
+	return ALPNPrefix + strconv.Itoa(version)
+}
+
+// WithALPN returns a copy of the TLS configuration with the ALPN identifier of ProtocolVersion
+// in front of its NextProtos, so peers supporting it prefer it to the other protocols.
+// The other protocols, like "quics-protocol" of older peers, are kept.
+func WithALPN(tlsConf *tls.Config) *tls.Config {
+	tlsConf = tlsConf.Clone()
+	alpn := ALPN(ProtocolVersion)
+	for _, proto := range tlsConf.NextProtos {
+		if proto == alpn {
+			return tlsConf
+		}
+	}
+	tlsConf.NextProtos = append([]string{alpn}, tlsConf.NextProtos...)
+	return tlsConf
+}
+
+// Capabilities are the capabilities exchanged by peers when a connection starts.
+type Capabilities struct {
+	// Version is the protocol version.
+	Version int
+	// Compression are the compression algorithms supported by the application, in order of preference.
+	Compression []string
+	// Checksums are the checksum algorithms supported, in order of preference.
+	Checksums []string
+	// MaxMessageSize is the maximum size of a message received. 0 means unlimited.
+	MaxMessageSize int64
+	// Datagrams reports whether QUIC datagrams are supported on the connection.
+	Datagrams bool
+	// Features are the names of the optional features supported by the application.
+	Features []string
+}
+
+// DefaultCapabilities are the capabilities sent by default.
+// The version, maximum message size and datagram support are filled from the connection when they are sent.
+var DefaultCapabilities = Capabilities{
+	Checksums: []string{"sha256"},
+}
+
+// Negotiate returns the capabilities supported by both peers.
+// The algorithms are in order of preference of the local capabilities,
+// and the maximum message size is the smaller one of both peers.
+// The maximum message size of the peer alone limits the messages sent to it. (see Stream.SetMaxSendMessageSize)
+func Negotiate(local Capabilities, peer Capabilities) Capabilities {
+	negotiated := Capabilities{
+		Version:        local.Version,
+		Compression:    intersect(local.Compression, peer.Compression),
+		Checksums:      intersect(local.Checksums, peer.Checksums),
+		MaxMessageSize: local.MaxMessageSize,
+		Datagrams:      local.Datagrams && peer.Datagrams,
+		Features:       intersect(local.Features, peer.Features),
+	}
+	if peer.Version < negotiated.Version {
+		negotiated.Version = peer.Version
+	}
+	if negotiated.MaxMessageSize <= 0 || (peer.MaxMessageSize > 0 && peer.MaxMessageSize < negotiated.MaxMessageSize) {
+		negotiated.MaxMessageSize = peer.MaxMessageSize
+	}
+	return negotiated
+}
+
+func intersect(local []string, peer []string) []string {
+	common := []string{}
+	for _, l := range local {
+		for _, p := range peer {
+			if l == p {
+				common = append(common, l)
+				break
+			}
+		}
+	}
+	return common
+}
+
+// Version returns the protocol version negotiated by ALPN.
+// It returns 0 if the peer does not support versioned ALPN identifiers, like older peers using "quics-protocol".
+// The capabilities are exchanged only from version 1.
+func (c *Connection) Version() int {
+	alpn := c.Conn.ConnectionState().TLS.NegotiatedProtocol
+	if !strings.HasPrefix(alpn, ALPNPrefix) {
+		return 0
+	}
+	version, err := strconv.Atoi(strings.TrimPrefix(alpn, ALPNPrefix))
+	if err != nil || version < 0 {
+		return 0
+	}
+	return version
+}
+
+// SetCapabilities sets the capabilities of this side of the connection.
+// This method is used internally by quics-protocol.
+// So, you may don't need to use it directly.
+func (c *Connection) SetCapabilities(capabilities Capabilities) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.capabilities = capabilities
+}
+
+// Capabilities returns the capabilities of this side of the connection that are sent to the peer.
+func (c *Connection) Capabilities() Capabilities {
+	c.mutex.Lock()
+	capabilities := c.capabilities
+	limit := c.limits.MaxMessageSize
+	c.mutex.Unlock()
+	capabilities.Version = c.Version()
+	if capabilities.MaxMessageSize == 0 && limit > 0 {
+		capabilities.MaxMessageSize = limit
+	}
+	capabilities.Datagrams = c.Conn.ConnectionState().SupportsDatagrams
+	return capabilities
+}
+
+// PeerCapabilities returns the capabilities of the peer.
+// It returns false if they are not exchanged, because the peer uses version 0 or the exchange is not finished yet.
+func (c *Connection) PeerCapabilities() (Capabilities, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.peerCapabilities == nil {
+		return Capabilities{}, false
+	}
+	return *c.peerCapabilities, true
+}
+
+// Hello exchanges the capabilities with the server on the client side.
+// This method is used internally when dialing.
+// So, you may don't need to use it directly.
+func (c *Connection) Hello() error {
+	return c.OpenTransaction(HelloTransactionName, func(stream *qpStream.Stream, transactionName string, transactionID []byte) error {
+		err := writeHello(stream, c.Capabilities())
+		if err != nil {
+			return err
+		}
+		peer, err := readHello(stream)
+		if err != nil {
+			return err
+		}
+		c.mutex.Lock()
+		c.peerCapabilities = peer
+		c.mutex.Unlock()
+		return nil
+	})
+}
+
+// RecvHello exchanges the capabilities with the client on the server side.
+// This method is used internally to handle transactions named HelloTransactionName.
+// So, you may don't need to use it directly.
+func (c *Connection) RecvHello(stream *qpStream.Stream) error {
+	peer, err := readHello(stream)
+	if err != nil {
+		return err
+	}
+	c.mutex.Lock()
+	c.peerCapabilities = peer
+	c.mutex.Unlock()
+	return writeHello(stream, c.Capabilities())
+}
+
+func writeHello(stream *qpStream.Stream, capabilities Capabilities) error {
+	requestId, err := uuid.New().MarshalBinary()
+	if err != nil {
+		return err
+	}
+	err = qpStream.WriteHeader(stream, pb.RequestType_HELLO, requestId, "")
+	if err != nil {
+		return err
+	}
+	helloOut, err := proto.Marshal(&pb.Hello{
+		Version:        uint32(capabilities.Version),
+		Compression:    capabilities.Compression,
+		Checksums:      capabilities.Checksums,
+		MaxMessageSize: capabilities.MaxMessageSize,
+		Datagrams:      capabilities.Datagrams,
+		Features:       capabilities.Features,
+	})
+	if err != nil {
+		return err
+	}
+	return qpStream.WriteMessage(stream, helloOut)
+}
+
+func readHello(stream *qpStream.Stream) (*Capabilities, error) {
+	header, err := qpStream.ReadHeader(stream)
+	if err != nil {
+		return nil, err
+	}
+	if header.RequestType != pb.RequestType_HELLO {
+		return nil, errors.New("request type is not Hello")
+	}
+	helloBuf, err := qpStream.ReadMessage(stream)
+	if err != nil {
+		return nil, err
+	}
+	hello := &pb.Hello{}
+	err = proto.Unmarshal(helloBuf, hello)
+	if err != nil {
+		return nil, err
+	}
+	return &Capabilities{
+		Version:        int(hello.Version),
+		Compression:    hello.Compression,
+		Checksums:      hello.Checksums,
+		MaxMessageSize: hello.MaxMessageSize,
+		Datagrams:      hello.Datagrams,
+		Features:       hello.Features,
+	}, nil
+}
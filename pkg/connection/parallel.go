@@ -0,0 +1,591 @@
+package connection
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/google/uuid"
+	qpErr "github.com/quic-s/quics-protocol/pkg/error"
+	qpLog "github.com/quic-s/quics-protocol/pkg/log"
+	qpStream "github.com/quic-s/quics-protocol/pkg/stream"
+	"github.com/quic-s/quics-protocol/pkg/types/fileinfo"
+	pb "github.com/quic-s/quics-protocol/proto/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// ReservedTransactionPrefix is the prefix of transaction names used internally by quics-protocol.
+	ReservedTransactionPrefix = "quics-protocol:"
+
+	// FileRangeTransactionName is the transaction name used to send each range of a file by SendFileParallel.
+	FileRangeTransactionName = ReservedTransactionPrefix + "file-range"
+
+	// MinFileRangeSize and MaxFileRangeSize bound the size of each range sent by SendFileParallel.
+	MinFileRangeSize = 1024 * 1024
+	MaxFileRangeSize = 64 * 1024 * 1024
+)
+
+// IsReservedTransactionName reports whether the name is one of the transaction names used internally by quics-protocol.
+// Transactions with other names starting with ReservedTransactionPrefix are rejected.
+func IsReservedTransactionName(transactionName string) bool {
+	switch transactionName {
+	case HelloTransactionName, AuthTransactionName, EnrollTransactionName, FileRangeTransactionName, ControlTransactionName:
+		return true
+	}
+	return false
+}
+
+// closedChan is a closed channel returned while ranges are expected. (see ReceivingRanges)
+var closedChan = func() chan struct{} {
+	c := make(chan struct{})
+	close(c)
+	return c
+}()
+
+// ReceivingRanges returns a channel that is closed while a file is received by RecvFileParallel on this connection.
+// This method is used internally to accept the ranges of parallel transfers beyond the queue of waiting transactions,
+// because the ranges are needed to finish the transaction receiving the file.
+// So, you may don't need to use it directly.
+func (c *Connection) ReceivingRanges() <-chan struct{} {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if len(c.transfers) > 0 {
+		return closedChan
+	}
+	if c.receivingRanges == nil {
+		c.receivingRanges = make(chan struct{})
+	}
+	return c.receivingRanges
+}
+
+// parallelTransfer is a file being received in ranges by RecvFileParallel.
+type parallelTransfer struct {
+	mutex      sync.Mutex
+	file       *os.File
+	size       int64
+	rangeSize  int64
+	rangeCount int64
+	hashes     map[int64][]byte
+	remaining  int64
+	done       chan struct{}
+	err        error
+}
+
+// complete records the verified hash of the range and reports whether all ranges are received.
+func (t *parallelTransfer) complete(index int64, hash []byte) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if _, ok := t.hashes[index]; ok {
+		return errors.New("file range is received twice")
+	}
+	t.hashes[index] = hash
+	t.remaining--
+	if t.remaining == 0 && t.err == nil {
+		close(t.done)
+	}
+	return nil
+}
+
+// fail stops waiting for the remaining ranges.
+func (t *parallelTransfer) fail(err error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.err != nil || t.remaining == 0 {
+		return
+	}
+	t.err = err
+	close(t.done)
+}
+
+// SendFileParallel sends a file split into ranges over multiple transactions at the same time.
+// The file is announced through the stream of the current transaction, and each range is sent through its own transaction,
+// so a lost packet only blocks the range it belongs to.
+// parallelism is the number of transactions used at the same time.
+// Each range is verified by its SHA-256 hash, and the receiving side verifies the whole file before writing it.
+// This method must be used in pairs with RecvFileParallel.
+func (c *Connection) SendFileParallel(stream *qpStream.Stream, filePath string, parallelism int) error {
+	if c == nil || c.Conn == nil {
+		return errors.New("connection instance is nil")
+	}
+	if stream == nil || stream.Stream == nil {
+		return errors.New("stream is nil")
+	}
+	if parallelism < 1 {
+		return errors.New("parallelism must be at least 1")
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		log.Println("quics-protocol: ", err)
+		return err
+	}
+	defer file.Close()
+
+	osFileInfo, err := file.Stat()
+	if err != nil {
+		log.Println("quics-protocol: ", err)
+		return err
+	}
+	if osFileInfo.IsDir() {
+		return errors.New("parallel transfer of a directory is not supported")
+	}
+	qpFileInfo, err := fileinfo.NewFromOSFileInfo(osFileInfo)
+	if err != nil {
+		log.Println("quics-protocol: ", err)
+		return err
+	}
+
+	transferID, err := uuid.New().MarshalBinary()
+	if err != nil {
+		return err
+	}
+	rangeSize := fileRangeSize(qpFileInfo.Size, parallelism)
+	rangeCount := (qpFileInfo.Size + rangeSize - 1) / rangeSize
+
+	requestId, err := uuid.New().MarshalBinary()
+	if err != nil {
+		return err
+	}
+	err = qpStream.WriteHeader(stream, pb.RequestType_PARALLEL_FILE, requestId, "")
+	if err != nil {
+		return err
+	}
+	err = qpStream.WriteFileInfo(stream, qpFileInfo)
+	if err != nil {
+		return err
+	}
+	parallelFileOut, err := proto.Marshal(&pb.ParallelFile{
+		TransferID: transferID,
+		RangeSize:  rangeSize,
+		RangeCount: rangeCount,
+	})
+	if err != nil {
+		return err
+	}
+	err = qpStream.WriteMessage(stream, parallelFileOut)
+	if err != nil {
+		return err
+	}
+
+	// Wait until the receiving side is ready to receive ranges.
+	header, err := qpStream.ReadHeader(stream)
+	if err != nil {
+		return err
+	}
+	if header.RequestType != pb.RequestType_PARALLEL_FILE {
+		return errors.New("request type is not ParallelFile")
+	}
+
+	if c.logLevel <= qpLog.INFO {
+		log.Println("quics-protocol: ", "sending", rangeCount, "ranges of", rangeSize, "bytes over", parallelism, "transactions")
+	}
+	hashes := make([][]byte, rangeCount)
+	indexes := make(chan int64)
+	errMutex := sync.Mutex{}
+	var sendErr error
+	wg := sync.WaitGroup{}
+	for w := int64(0); w < int64(parallelism) && w < rangeCount; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				offset := i * rangeSize
+				length := rangeSize
+				if offset+length > qpFileInfo.Size {
+					length = qpFileInfo.Size - offset
+				}
+				hash, err := c.sendFileRange(file, transferID, i, offset, length)
+				if err != nil {
+					log.Println("quics-protocol: ", err)
+					errMutex.Lock()
+					if sendErr == nil {
+						sendErr = err
+					}
+					errMutex.Unlock()
+					continue
+				}
+				hashes[i] = hash
+			}
+		}()
+	}
+	for i := int64(0); i < rangeCount; i++ {
+		errMutex.Lock()
+		failed := sendErr != nil
+		errMutex.Unlock()
+		if failed {
+			break
+		}
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	if sendErr == nil {
+		afterFileInfo, err := file.Stat()
+		if err != nil {
+			sendErr = err
+		} else if qpFileInfo.ModTime != afterFileInfo.ModTime() || qpFileInfo.Size != afterFileInfo.Size() || qpFileInfo.Mode != afterFileInfo.Mode() {
+			log.Println("quics-protocol: file is modified during transfer")
+			sendErr = qpErr.ErrFileModifiedDuringTransfer
+		}
+	}
+
+	requestId, err = uuid.New().MarshalBinary()
+	if err != nil {
+		return err
+	}
+	if sendErr != nil {
+		qpStream.WriteHeader(stream, pb.RequestType_PARALLEL_FILE, requestId, sendErr.Error())
+		return sendErr
+	}
+	err = qpStream.WriteHeader(stream, pb.RequestType_PARALLEL_FILE, requestId, "")
+	if err != nil {
+		return err
+	}
+	endOut, err := proto.Marshal(&pb.ParallelFileEnd{
+		RangeHashes: hashes,
+	})
+	if err != nil {
+		return err
+	}
+	err = qpStream.WriteMessage(stream, endOut)
+	if err != nil {
+		return err
+	}
+
+	// Wait until the receiving side verifies and writes the file.
+	header, err = qpStream.ReadHeader(stream)
+	if err != nil {
+		return err
+	}
+	if header.RequestType != pb.RequestType_PARALLEL_FILE {
+		return errors.New("request type is not ParallelFile")
+	}
+	return nil
+}
+
+// RecvFileParallel receives a file sent by SendFileParallel and writes it to the file path.
+// The stream of the current transaction is used to receive the announcement of the file,
+// and the ranges received through other transactions are written at their offsets of a temporary file next to the file path.
+// After all ranges are received, the temporary file is verified and moved to the file path with its metadata.
+// The file metadata is returned as a result.
+// This method must be used in pairs with SendFileParallel.
+func (c *Connection) RecvFileParallel(stream *qpStream.Stream, filePath string) (*fileinfo.FileInfo, error) {
+	if c == nil || c.Conn == nil {
+		return nil, errors.New("connection instance is nil")
+	}
+	if stream == nil || stream.Stream == nil {
+		return nil, errors.New("stream is nil")
+	}
+
+	header, err := qpStream.ReadHeader(stream)
+	if err != nil {
+		return nil, err
+	}
+	if header.RequestType != pb.RequestType_PARALLEL_FILE {
+		return nil, errors.New("request type is not ParallelFile")
+	}
+	fileInfo, err := qpStream.ReadFileInfo(stream)
+	if err != nil {
+		return nil, err
+	}
+	parallelFileBuf, err := qpStream.ReadMessage(stream)
+	if err != nil {
+		return nil, err
+	}
+	parallelFile := &pb.ParallelFile{}
+	err = proto.Unmarshal(parallelFileBuf, parallelFile)
+	if err != nil {
+		return nil, err
+	}
+	// The file size is already checked against the limits by ReadFileInfo.
+	// The range size is bounded, so the number of ranges stays reasonable for the size of the file.
+	if fileInfo.IsDir || fileInfo.Size < 0 || parallelFile.RangeSize < MinFileRangeSize || parallelFile.RangeSize > MaxFileRangeSize {
+		return nil, errors.New("invalid parallel file")
+	}
+	rangeCount := fileInfo.Size / parallelFile.RangeSize
+	if fileInfo.Size%parallelFile.RangeSize != 0 {
+		rangeCount++
+	}
+	if parallelFile.RangeCount != rangeCount {
+		return nil, errors.New("invalid parallel file")
+	}
+
+	dir, name := filepath.Split(filePath)
+	if dir != "" {
+		err = os.MkdirAll(dir, 0700)
+		if err != nil {
+			return nil, err
+		}
+	}
+	tmp, err := os.CreateTemp(dir, "."+name+".parallel-*")
+	if err != nil {
+		return nil, err
+	}
+	committed := false
+	defer func() {
+		tmp.Close()
+		if !committed {
+			os.Remove(tmp.Name())
+		}
+	}()
+	err = tmp.Truncate(fileInfo.Size)
+	if err != nil {
+		return nil, err
+	}
+
+	transfer := &parallelTransfer{
+		file:       tmp,
+		size:       fileInfo.Size,
+		rangeSize:  parallelFile.RangeSize,
+		rangeCount: parallelFile.RangeCount,
+		hashes:     make(map[int64][]byte),
+		remaining:  parallelFile.RangeCount,
+		done:       make(chan struct{}),
+	}
+	if transfer.remaining == 0 {
+		close(transfer.done)
+	}
+	transferKey := string(parallelFile.TransferID)
+	c.mutex.Lock()
+	c.transfers[transferKey] = transfer
+	if c.receivingRanges != nil {
+		close(c.receivingRanges)
+		c.receivingRanges = nil
+	}
+	c.mutex.Unlock()
+	defer func() {
+		c.mutex.Lock()
+		delete(c.transfers, transferKey)
+		c.mutex.Unlock()
+	}()
+
+	requestId, err := uuid.New().MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	err = qpStream.WriteHeader(stream, pb.RequestType_PARALLEL_FILE, requestId, "")
+	if err != nil {
+		return nil, err
+	}
+
+	// The sending side sends an error through this header if sending any range failed.
+	header, err = qpStream.ReadHeader(stream)
+	if err != nil {
+		return nil, err
+	}
+	if header.RequestType != pb.RequestType_PARALLEL_FILE {
+		return nil, errors.New("request type is not ParallelFile")
+	}
+	endBuf, err := qpStream.ReadMessage(stream)
+	if err != nil {
+		return nil, err
+	}
+	end := &pb.ParallelFileEnd{}
+	err = proto.Unmarshal(endBuf, end)
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(end.RangeHashes)) != transfer.rangeCount {
+		return nil, errors.New("number of range hashes is not equal with range count")
+	}
+
+	select {
+	case <-transfer.done:
+	case <-c.Conn.Context().Done():
+		return nil, c.Conn.Context().Err()
+	}
+	if transfer.err != nil {
+		return nil, transfer.err
+	}
+
+	// Verify the whole file written on the disk against the hashes of each range.
+	for i := int64(0); i < transfer.rangeCount; i++ {
+		if !bytes.Equal(transfer.hashes[i], end.RangeHashes[i]) {
+			return nil, qpErr.ErrFileRangeChecksumMismatch
+		}
+		offset := i * transfer.rangeSize
+		length := transfer.rangeSize
+		if offset+length > transfer.size {
+			length = transfer.size - offset
+		}
+		hash := sha256.New()
+		_, err := io.Copy(hash, io.NewSectionReader(tmp, offset, length))
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(hash.Sum(nil), end.RangeHashes[i]) {
+			return nil, qpErr.ErrFileRangeChecksumMismatch
+		}
+	}
+
+	err = tmp.Close()
+	if err != nil {
+		return nil, err
+	}
+	err = fileInfo.CommitFile(tmp.Name(), filePath, nil)
+	if err != nil {
+		return nil, err
+	}
+	committed = true
+
+	requestId, err = uuid.New().MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	err = qpStream.WriteHeader(stream, pb.RequestType_PARALLEL_FILE, requestId, "")
+	if err != nil {
+		return nil, err
+	}
+	return fileInfo, nil
+}
+
+// RecvFileRange receives a range of a file sent by SendFileParallel and writes it at its offset.
+// This method is used internally to handle transactions named FileRangeTransactionName.
+// So, you may don't need to use it directly.
+func (c *Connection) RecvFileRange(stream *qpStream.Stream) error {
+	header, err := qpStream.ReadHeader(stream)
+	if err != nil {
+		return err
+	}
+	if header.RequestType != pb.RequestType_FILE_RANGE {
+		return errors.New("request type is not FileRange")
+	}
+	fileRangeBuf, err := qpStream.ReadMessage(stream)
+	if err != nil {
+		return err
+	}
+	fileRange := &pb.FileRange{}
+	err = proto.Unmarshal(fileRangeBuf, fileRange)
+	if err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	transfer := c.transfers[string(fileRange.TransferID)]
+	c.mutex.Unlock()
+	if transfer == nil {
+		return errors.New("file range of unknown transfer")
+	}
+
+	err = c.recvFileRange(stream, transfer, fileRange)
+	if err != nil {
+		transfer.fail(err)
+		return err
+	}
+
+	requestId, err := uuid.New().MarshalBinary()
+	if err != nil {
+		return err
+	}
+	return qpStream.WriteHeader(stream, pb.RequestType_FILE_RANGE, requestId, "")
+}
+
+func (c *Connection) recvFileRange(stream *qpStream.Stream, transfer *parallelTransfer, fileRange *pb.FileRange) error {
+	index := fileRange.Index
+	if index < 0 || index >= transfer.rangeCount {
+		return errors.New("file range index is out of range")
+	}
+	length := transfer.rangeSize
+	if index*transfer.rangeSize+length > transfer.size {
+		length = transfer.size - index*transfer.rangeSize
+	}
+	if fileRange.Offset != index*transfer.rangeSize || fileRange.Length != length {
+		return errors.New("invalid file range")
+	}
+
+	hash := sha256.New()
+	writer := io.MultiWriter(io.NewOffsetWriter(transfer.file, fileRange.Offset), hash)
+	n, err := io.CopyN(writer, stream.Reader(), fileRange.Length)
+	if err != nil {
+		return err
+	}
+	if n != fileRange.Length {
+		return errors.New("file range size is not equal with range length")
+	}
+	sum, err := qpStream.ReadMessage(stream)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(sum, hash.Sum(nil)) {
+		return qpErr.ErrFileRangeChecksumMismatch
+	}
+	if c.logLevel <= qpLog.INFO {
+		log.Println("quics-protocol: ", "received file range", index, "of", transfer.rangeCount)
+	}
+	return transfer.complete(index, sum)
+}
+
+// sendFileRange sends a range of the file through a new transaction and returns the hash of the range.
+func (c *Connection) sendFileRange(file *os.File, transferID []byte, index int64, offset int64, length int64) ([]byte, error) {
+	var sum []byte
+	err := c.OpenTransaction(FileRangeTransactionName, func(stream *qpStream.Stream, transactionName string, transactionID []byte) error {
+		requestId, err := uuid.New().MarshalBinary()
+		if err != nil {
+			return err
+		}
+		err = qpStream.WriteHeader(stream, pb.RequestType_FILE_RANGE, requestId, "")
+		if err != nil {
+			return err
+		}
+		fileRangeOut, err := proto.Marshal(&pb.FileRange{
+			TransferID: transferID,
+			Index:      index,
+			Offset:     offset,
+			Length:     length,
+		})
+		if err != nil {
+			return err
+		}
+		err = qpStream.WriteMessage(stream, fileRangeOut)
+		if err != nil {
+			return err
+		}
+
+		hash := sha256.New()
+		n, err := io.CopyN(io.MultiWriter(stream.Writer(), hash), io.NewSectionReader(file, offset, length), length)
+		if err != nil {
+			return err
+		}
+		if n != length {
+			return errors.New("write size is not equal to range length")
+		}
+		sum = hash.Sum(nil)
+		err = qpStream.WriteMessage(stream, sum)
+		if err != nil {
+			return err
+		}
+
+		// Wait until the receiving side writes the range.
+		header, err := qpStream.ReadHeader(stream)
+		if err != nil {
+			return err
+		}
+		if header.RequestType != pb.RequestType_FILE_RANGE {
+			return errors.New("request type is not FileRange")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sum, nil
+}
+
+// fileRangeSize returns the size of each range, so that each transaction sends a few ranges.
+func fileRangeSize(size int64, parallelism int) int64 {
+	rangeSize := (size + int64(parallelism)*4 - 1) / (int64(parallelism) * 4)
+	if rangeSize < MinFileRangeSize {
+		return MinFileRangeSize
+	}
+	if rangeSize > MaxFileRangeSize {
+		return MaxFileRangeSize
+	}
+	return rangeSize
+}
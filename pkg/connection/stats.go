@@ -0,0 +1,136 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	qpErr "github.com/quic-s/quics-protocol/pkg/error"
+	qpLog "github.com/quic-s/quics-protocol/pkg/log"
+)
+
+// Stats are the statistics of a connection.
+type Stats struct {
+	// RTT is the round-trip time measured by the last ping. It is 0 if no ping is answered yet. (see Ping)
+	RTT time.Duration
+	// SmoothedRTT is the exponentially weighted moving average of the round-trip times, like the one of TCP.
+	SmoothedRTT time.Duration
+	// MinRTT is the smallest round-trip time measured.
+	MinRTT time.Duration
+	// BytesSent and BytesReceived are the bytes sent and received in all transactions, including the protocol messages.
+	BytesSent     int64
+	BytesReceived int64
+	// RequestsSent and RequestsReceived are the requests, like messages and files, sent and received in all transactions.
+	RequestsSent     int64
+	RequestsReceived int64
+	// OpenTransactions is the number of transactions being opened or handled on this connection.
+	OpenTransactions int
+	// CompletedTransactions is the number of transactions finished on this connection.
+	CompletedTransactions int64
+	// Age is the time since the connection is established.
+	Age time.Duration
+}
+
+// AggregateStats are the statistics of multiple connections, like the open connections of a quics-protocol instance.
+type AggregateStats struct {
+	// Connections is the number of connections.
+	Connections int
+	// SmoothedRTT is the average of the smoothed round-trip times of the connections that measured one.
+	SmoothedRTT time.Duration
+	// MaxRTT is the largest smoothed round-trip time of the connections, to find slow peers.
+	MaxRTT time.Duration
+	// The other fields are the sums of the fields of the connections.
+	BytesSent             int64
+	BytesReceived         int64
+	RequestsSent          int64
+	RequestsReceived      int64
+	OpenTransactions      int
+	CompletedTransactions int64
+}
+
+// Aggregate sums the statistics of the connections.
+func Aggregate(stats []Stats) AggregateStats {
+	aggregate := AggregateStats{
+		Connections: len(stats),
+	}
+	measured := 0
+	var totalRTT time.Duration
+	for _, s := range stats {
+		aggregate.BytesSent += s.BytesSent
+		aggregate.BytesReceived += s.BytesReceived
+		aggregate.RequestsSent += s.RequestsSent
+		aggregate.RequestsReceived += s.RequestsReceived
+		aggregate.OpenTransactions += s.OpenTransactions
+		aggregate.CompletedTransactions += s.CompletedTransactions
+		if s.SmoothedRTT > 0 {
+			measured++
+			totalRTT += s.SmoothedRTT
+		}
+		if s.SmoothedRTT > aggregate.MaxRTT {
+			aggregate.MaxRTT = s.SmoothedRTT
+		}
+	}
+	if measured > 0 {
+		aggregate.SmoothedRTT = totalRTT / time.Duration(measured)
+	}
+	return aggregate
+}
+
+// Stats returns the statistics of this connection.
+// The round-trip times are measured by the pings on the control stream. (see Ping and KeepPinging)
+func (c *Connection) Stats() Stats {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return Stats{
+		RTT:                   c.rtt,
+		SmoothedRTT:           c.smoothedRTT,
+		MinRTT:                c.minRTT,
+		BytesSent:             c.counters.BytesSent.Load(),
+		BytesReceived:         c.counters.BytesReceived.Load(),
+		RequestsSent:          c.counters.RequestsSent.Load(),
+		RequestsReceived:      c.counters.RequestsReceived.Load(),
+		OpenTransactions:      c.openTransactions,
+		CompletedTransactions: c.completedTransactions,
+		Age:                   time.Since(c.created),
+	}
+}
+
+// KeepPinging pings the peer every interval in a new goroutine until the connection is closed,
+// so the round-trip times of Stats are kept up to date.
+// The pings are skipped while the control stream is not opened.
+func (c *Connection) KeepPinging(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				ctx, cancel := context.WithTimeout(c.Conn.Context(), interval)
+				_, err := c.Ping(ctx)
+				cancel()
+				if err != nil && !errors.Is(err, qpErr.ErrNoControlStream) && c.logLevel <= qpLog.INFO {
+					log.Println("quics-protocol: ", "ping failed: ", err)
+				}
+			case <-c.Conn.Context().Done():
+				return
+			}
+		}
+	}()
+}
+
+// recordRTT updates the round-trip times with a measured one.
+// The smoothed round-trip time is updated like the one of TCP (RFC 6298).
+func (c *Connection) recordRTT(rtt time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.rtt = rtt
+	if c.smoothedRTT == 0 {
+		c.smoothedRTT = rtt
+	} else {
+		c.smoothedRTT = (7*c.smoothedRTT + rtt) / 8
+	}
+	if c.minRTT == 0 || rtt < c.minRTT {
+		c.minRTT = rtt
+	}
+}
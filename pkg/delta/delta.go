@@ -0,0 +1,342 @@
+package delta
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"math"
+)
+
+const (
+	// MinBlockSize and MaxBlockSize bound the block size chosen by BlockSizeFor.
+	MinBlockSize = 2 * 1024
+	MaxBlockSize = 128 * 1024
+
+	// MaxLiteralSize is the maximum size of the data carried by a single literal instruction.
+	MaxLiteralSize = 64 * 1024
+
+	// modulus of the rolling checksum components.
+	weakMod = 1 << 16
+)
+
+// OpType is the type of delta instruction.
+type OpType int
+
+const (
+	// OpCopy copies BlockCount blocks starting from BlockIndex of the receiver's existing file.
+	OpCopy OpType = iota
+	// OpLiteral writes Data as it is.
+	OpLiteral
+)
+
+// BlockSignature is the signature of a single block of the receiver's existing file.
+type BlockSignature struct {
+	Weak   uint32
+	Strong []byte
+}
+
+// Signature is the list of block signatures of the receiver's existing file.
+// The last block can be shorter than BlockSize.
+type Signature struct {
+	BlockSize int
+	Blocks    []BlockSignature
+	// length of the last block. It is not sent through the connection and is restored from FileSize.
+	lastBlockSize int
+}
+
+// Op is a single delta instruction.
+type Op struct {
+	Type       OpType
+	BlockIndex int64
+	BlockCount int64
+	Data       []byte
+}
+
+// BlockSizeFor returns the block size to use for a file of the given size.
+// Like rsync, it is roughly the square root of the file size.
+func BlockSizeFor(size int64) int {
+	blockSize := int(math.Sqrt(float64(size)))
+	blockSize = (blockSize + 7) &^ 7
+	if blockSize < MinBlockSize {
+		return MinBlockSize
+	}
+	if blockSize > MaxBlockSize {
+		return MaxBlockSize
+	}
+	return blockSize
+}
+
+// NewSignature reads r until EOF and returns the signatures of each block of blockSize bytes.
+func NewSignature(r io.Reader, blockSize int) (*Signature, error) {
+	if blockSize <= 0 {
+		return nil, errors.New("block size must be positive")
+	}
+	sig := &Signature{
+		BlockSize: blockSize,
+		Blocks:    []BlockSignature{},
+	}
+	buf := make([]byte, blockSize)
+	for {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			strong := sha256.Sum256(buf[:n])
+			sig.Blocks = append(sig.Blocks, BlockSignature{
+				Weak:   weakSum(buf[:n]),
+				Strong: strong[:],
+			})
+			sig.lastBlockSize = n
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return sig, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// SetFileSize restores the length of the last block from the size of the file the signature was made from.
+// This is needed for signatures received through the connection to match the last, shorter block.
+func (sig *Signature) SetFileSize(size int64) {
+	if len(sig.Blocks) == 0 {
+		sig.lastBlockSize = 0
+		return
+	}
+	sig.lastBlockSize = int(size - int64(len(sig.Blocks)-1)*int64(sig.BlockSize))
+}
+
+// FileSize returns the size of the file the signature was made from.
+func (sig *Signature) FileSize() int64 {
+	if len(sig.Blocks) == 0 {
+		return 0
+	}
+	return int64(len(sig.Blocks)-1)*int64(sig.BlockSize) + int64(sig.lastBlockSize)
+}
+
+// Generate reads the new file content from r and calls emit with the instructions
+// to rebuild it from the file described by sig.
+// Consecutive copied blocks are merged into a single instruction.
+// The Data of a literal instruction is only valid until emit returns.
+func Generate(sig *Signature, r io.Reader, emit func(op *Op) error) error {
+	if sig == nil || sig.BlockSize <= 0 {
+		return errors.New("invalid signature")
+	}
+	blockSize := sig.BlockSize
+
+	index := make(map[uint32][]int64, len(sig.Blocks))
+	for i, block := range sig.Blocks {
+		index[block.Weak] = append(index[block.Weak], int64(i))
+	}
+
+	g := &generator{
+		sig:     sig,
+		emit:    emit,
+		literal: make([]byte, 0, MaxLiteralSize),
+		pending: Op{Type: OpCopy, BlockIndex: -1},
+	}
+
+	// buf holds the data read from r. The current window is buf[start:end].
+	buf := make([]byte, 0, 4*blockSize)
+	start := 0
+	eof := false
+	fill := func() error {
+		if eof {
+			return nil
+		}
+		if start > 0 && start+blockSize > cap(buf) {
+			n := copy(buf[:cap(buf)], buf[start:])
+			buf = buf[:n]
+			start = 0
+		}
+		for len(buf)-start < blockSize && !eof {
+			n, err := r.Read(buf[len(buf):cap(buf)])
+			buf = buf[:len(buf)+n]
+			if err == io.EOF {
+				eof = true
+			} else if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := fill(); err != nil {
+		return err
+	}
+	var a, b uint32
+	rolling := false
+	for {
+		window := len(buf) - start
+		if window > blockSize {
+			window = blockSize
+		}
+		if window == 0 {
+			break
+		}
+
+		if window == blockSize || window == sig.lastBlockSize {
+			if !rolling {
+				a, b = weakParts(buf[start : start+window])
+				rolling = true
+			}
+			match := g.find(index, buf[start:start+window], a|b<<16)
+			if match >= 0 {
+				if err := g.copyBlock(match); err != nil {
+					return err
+				}
+				start += window
+				rolling = false
+				if err := fill(); err != nil {
+					return err
+				}
+				continue
+			}
+		} else {
+			rolling = false
+		}
+
+		// No block matched. Move the first byte of the window to the literal data.
+		out := buf[start]
+		if err := g.literalByte(out); err != nil {
+			return err
+		}
+		start++
+		if err := fill(); err != nil {
+			return err
+		}
+		if rolling {
+			if len(buf)-start >= blockSize {
+				in := buf[start+blockSize-1]
+				a = (a - uint32(out) + uint32(in)) % weakMod
+				b = (b - uint32(blockSize)*uint32(out) + a) % weakMod
+			} else {
+				rolling = false
+			}
+		}
+	}
+	return g.flush()
+}
+
+// Apply writes the data described by op to w, reading copied blocks from base.
+func Apply(base io.ReaderAt, blockSize int, op *Op, w io.Writer) error {
+	switch op.Type {
+	case OpCopy:
+		if op.BlockIndex < 0 || op.BlockCount <= 0 {
+			return errors.New("invalid copy instruction")
+		}
+		offset := op.BlockIndex * int64(blockSize)
+		length := op.BlockCount * int64(blockSize)
+		_, err := io.Copy(w, io.NewSectionReader(base, offset, length))
+		return err
+	case OpLiteral:
+		_, err := w.Write(op.Data)
+		return err
+	default:
+		return errors.New("unknown delta instruction")
+	}
+}
+
+type generator struct {
+	sig     *Signature
+	emit    func(op *Op) error
+	literal []byte
+	pending Op
+}
+
+func (g *generator) find(index map[uint32][]int64, window []byte, weak uint32) int64 {
+	candidates, ok := index[weak]
+	if !ok {
+		return -1
+	}
+	var strong [sha256.Size]byte
+	computed := false
+	for _, i := range candidates {
+		if g.blockLen(i) != len(window) {
+			continue
+		}
+		if !computed {
+			strong = sha256.Sum256(window)
+			computed = true
+		}
+		if bytes.Equal(g.sig.Blocks[i].Strong, strong[:]) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (g *generator) blockLen(i int64) int {
+	if i == int64(len(g.sig.Blocks)-1) {
+		return g.sig.lastBlockSize
+	}
+	return g.sig.BlockSize
+}
+
+func (g *generator) copyBlock(i int64) error {
+	if err := g.flushLiteral(); err != nil {
+		return err
+	}
+	if g.pending.BlockIndex >= 0 && g.pending.BlockIndex+g.pending.BlockCount == i {
+		g.pending.BlockCount++
+		return nil
+	}
+	if err := g.flushCopy(); err != nil {
+		return err
+	}
+	g.pending.BlockIndex = i
+	g.pending.BlockCount = 1
+	return nil
+}
+
+func (g *generator) literalByte(c byte) error {
+	if err := g.flushCopy(); err != nil {
+		return err
+	}
+	g.literal = append(g.literal, c)
+	if len(g.literal) >= MaxLiteralSize {
+		return g.flushLiteral()
+	}
+	return nil
+}
+
+func (g *generator) flushCopy() error {
+	if g.pending.BlockIndex < 0 {
+		return nil
+	}
+	op := g.pending
+	g.pending.BlockIndex = -1
+	g.pending.BlockCount = 0
+	return g.emit(&op)
+}
+
+func (g *generator) flushLiteral() error {
+	if len(g.literal) == 0 {
+		return nil
+	}
+	err := g.emit(&Op{Type: OpLiteral, Data: g.literal})
+	g.literal = g.literal[:0]
+	return err
+}
+
+func (g *generator) flush() error {
+	if err := g.flushCopy(); err != nil {
+		return err
+	}
+	return g.flushLiteral()
+}
+
+func weakParts(data []byte) (uint32, uint32) {
+	var a, b uint32
+	l := uint32(len(data))
+	for i, c := range data {
+		a += uint32(c)
+		b += (l - uint32(i)) * uint32(c)
+	}
+	return a % weakMod, b % weakMod
+}
+
+func weakSum(data []byte) uint32 {
+	a, b := weakParts(data)
+	return a | b<<16
+}
@@ -0,0 +1,86 @@
+package enrollment
+
+import (
+	"crypto/x509"
+	"net"
+	"sync"
+	"time"
+
+	qpTls "github.com/quic-s/quics-protocol/pkg/tls"
+)
+
+// Enrollment issues client certificates to the clients that request them over a connection.
+// A new client sends a bootstrap token with a certificate signing request (CSR),
+// and a client with a certificate issued before renews it over a connection authenticated by that certificate.
+// The private key of the client never leaves the client.
+type Enrollment struct {
+	// CA signs the approved requests.
+	CA *qpTls.CA
+	// Approve decides whether the request is approved, and returns the options of the issued certificate.
+	// The request is refused if it returns an error. The key type of the options is ignored.
+	Approve func(request *Request) (qpTls.CertificateOptions, error)
+}
+
+// Request is a certificate signing request received from a client.
+type Request struct {
+	// Token is the bootstrap token sent by the client. It is empty when the client renews its certificate.
+	Token string
+	// CertificateRequest is the parsed CSR. Its signature is already checked.
+	CertificateRequest *x509.CertificateRequest
+	// Peer is the identity of the client certificate of the connection, or nil if the client did not send one.
+	// The server must be configured to verify client certificates if given (tls.VerifyClientCertIfGiven)
+	// so new clients can connect without a certificate.
+	Peer *qpTls.Identity
+	// RemoteAddr is the address of the client.
+	RemoteAddr net.Addr
+}
+
+// Options returns the certificate options of the subject and SANs requested by the CSR with the validity.
+func (r *Request) Options(validity time.Duration) qpTls.CertificateOptions {
+	csr := r.CertificateRequest
+	hosts := append([]string{}, csr.DNSNames...)
+	for _, ip := range csr.IPAddresses {
+		hosts = append(hosts, ip.String())
+	}
+	return qpTls.CertificateOptions{
+		CommonName: csr.Subject.CommonName,
+		Hosts:      hosts,
+		Validity:   validity,
+	}
+}
+
+// BootstrapTokens are one-time tokens handed out to new clients to enroll.
+type BootstrapTokens struct {
+	mutex  sync.Mutex
+	tokens map[string]time.Time
+}
+
+// NewBootstrapTokens creates a new empty set of bootstrap tokens.
+func NewBootstrapTokens() *BootstrapTokens {
+	return &BootstrapTokens{
+		tokens: make(map[string]time.Time),
+	}
+}
+
+// Add adds the token that is valid for the ttl. A ttl of 0 or less means the token never expires.
+func (b *BootstrapTokens) Add(token string, ttl time.Duration) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	expiry := time.Time{}
+	if ttl > 0 {
+		expiry = time.Now().Add(ttl)
+	}
+	b.tokens[token] = expiry
+}
+
+// Use reports whether the token is valid and removes it, so each token is used only once.
+func (b *BootstrapTokens) Use(token string) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	expiry, ok := b.tokens[token]
+	if !ok {
+		return false
+	}
+	delete(b.tokens, token)
+	return expiry.IsZero() || time.Now().Before(expiry)
+}
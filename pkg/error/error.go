@@ -1,6 +1,9 @@
 package error
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 const (
 	ConnectionClosedByPeer = "Application error 0x0 (remote): Connection closed by peer"
@@ -8,8 +11,61 @@ const (
 	NoRecentActivity = "timeout: no recent network activity"
 
 	FileModifiedDuringTransferCode = 0x1
+
+	LimitExceededCode = 0x2
+
+	HandshakeTimeoutCode = 0x3
+
+	IdleTimeoutCode = 0x4
+
+	TooManyTimeoutsCode = 0x5
+
+	ConnectionRejectedCode = 0x6
+
+	UnauthenticatedCode = 0x7
+
+	AuthenticationFailedCode = 0x8
+
+	UnknownTransactionCode = 0x9
 )
 
 var (
 	ErrFileModifiedDuringTransfer = errors.New("file modified during transfer")
+
+	ErrDeltaChecksumMismatch = errors.New("checksum of the file rebuilt from delta is not matched")
+
+	ErrFileRangeChecksumMismatch = errors.New("checksum of the file range is not matched")
+
+	ErrHandshakeTimeout = errors.New("transaction handshake timed out")
+
+	ErrIdleTimeout = errors.New("transaction timed out with no recent activity")
+
+	ErrConnectionDenied = errors.New("connection from the address is denied")
+
+	ErrTooManyConnections = errors.New("too many connections from the address")
+
+	ErrUnauthenticated = errors.New("connection is not authenticated")
+
+	ErrAuthenticationFailed = errors.New("authentication failed")
+
+	ErrGoingAway = errors.New("connection is going away")
+
+	ErrNoControlStream = errors.New("control stream is not opened")
 )
+
+// LimitError is returned when the data received from the peer exceeds a receive limit.
+// The stream is reset with LimitExceededCode.
+// It is also returned when a message to send exceeds the maximum message size received by the peer.
+// Then, the message is not sent, and the stream is reset only if the request of the message is already started.
+type LimitError struct {
+	// Limit is the name of the exceeded limit.
+	Limit string
+	// Value is the value received from the peer.
+	Value int64
+	// Max is the configured limit.
+	Max int64
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%s %d exceeds the limit %d", e.Limit, e.Value, e.Max)
+}
@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"context"
+	"sync"
+)
+
+// Concurrency limits the transactions handled at the same time. A limit of 0 or less means unlimited.
+// Accepted transactions wait in the queue until they can be handled within the limits.
+// When the queue is full, no more streams are accepted,
+// so QUIC flow control makes the peers wait before opening more transactions.
+// Transactions used internally by quics-protocol, like the ranges of parallel transfers, are not limited after they are accepted.
+// While a connection receives a file with RecvFileParallel, its streams are accepted even if the queue is full,
+// so the ranges are not blocked behind the transactions waiting for the transaction receiving the file.
+// The other transactions accepted this way wait for room in the queue before they wait for the limits.
+type Concurrency struct {
+	// MaxTransactions is the maximum number of transactions handled at the same time over all connections.
+	MaxTransactions int
+	// MaxTransactionsPerConnection is the maximum number of transactions of a connection handled at the same time.
+	MaxTransactionsPerConnection int
+	// MaxTransactionsPerName is the maximum number of transactions of each transaction name handled at the same time.
+	MaxTransactionsPerName map[string]int
+	// MaxQueue is the maximum number of accepted transactions waiting to be handled,
+	// including the transactions whose handshake is not received yet.
+	// It is applied over all connections and to each connection.
+	MaxQueue int
+}
+
+// semaphore limits the number of holders. A nil semaphore is unlimited.
+type semaphore chan struct{}
+
+func newSemaphore(limit int) semaphore {
+	if limit <= 0 {
+		return nil
+	}
+	return make(semaphore, limit)
+}
+
+// acquire waits until the semaphore is acquired or one of the contexts is done.
+func (s semaphore) acquire(ctx context.Context, connCtx context.Context) error {
+	if s == nil {
+		return nil
+	}
+	select {
+	case s <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-connCtx.Done():
+		return connCtx.Err()
+	}
+}
+
+// acquireUnless waits until the semaphore is acquired or the bypass channel is closed, and reports whether it is acquired.
+// It returns an error if one of the contexts is done before.
+func (s semaphore) acquireUnless(ctx context.Context, connCtx context.Context, bypass <-chan struct{}) (bool, error) {
+	if s == nil {
+		return true, nil
+	}
+	select {
+	case s <- struct{}{}:
+		return true, nil
+	case <-bypass:
+		return false, nil
+	case <-ctx.Done():
+		return false, ctx.Err()
+	case <-connCtx.Done():
+		return false, connCtx.Err()
+	}
+}
+
+func (s semaphore) release() {
+	if s == nil {
+		return
+	}
+	<-s
+}
+
+// workers are the semaphores of a concurrency setting shared by all connections.
+type workers struct {
+	concurrency Concurrency
+	queue       semaphore
+	running     semaphore
+
+	mutex sync.Mutex
+	names map[string]semaphore
+}
+
+func newWorkers(concurrency Concurrency) *workers {
+	names := make(map[string]int, len(concurrency.MaxTransactionsPerName))
+	for name, limit := range concurrency.MaxTransactionsPerName {
+		names[name] = limit
+	}
+	concurrency.MaxTransactionsPerName = names
+	return &workers{
+		concurrency: concurrency,
+		queue:       newSemaphore(concurrency.MaxQueue),
+		running:     newSemaphore(concurrency.MaxTransactions),
+		names:       make(map[string]semaphore),
+	}
+}
+
+// name returns the semaphore of the transaction name.
+func (w *workers) name(transactionName string) semaphore {
+	limit := w.concurrency.MaxTransactionsPerName[transactionName]
+	if limit <= 0 {
+		return nil
+	}
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.names[transactionName] == nil {
+		w.names[transactionName] = newSemaphore(limit)
+	}
+	return w.names[transactionName]
+}
+
+// SetConcurrency sets the limits of the transactions handled at the same time.
+// The limits are applied to the connections routed after it is called.
+func (h *Handler) SetConcurrency(concurrency Concurrency) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.workers = newWorkers(concurrency)
+}
+
+func (h *Handler) getWorkers() *workers {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.workers
+}
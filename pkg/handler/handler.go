@@ -4,18 +4,31 @@ import (
 	"context"
 	"errors"
 	"log"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/quic-go/quic-go"
 	qpConn "github.com/quic-s/quics-protocol/pkg/connection"
+	qpErr "github.com/quic-s/quics-protocol/pkg/error"
 	qpLog "github.com/quic-s/quics-protocol/pkg/log"
+	"github.com/quic-s/quics-protocol/pkg/metrics"
 	qpStream "github.com/quic-s/quics-protocol/pkg/stream"
 )
 
+// unknownReservedMetricName is the transaction name reported to the metrics for the unknown reserved transactions.
+const unknownReservedMetricName = qpConn.ReservedTransactionPrefix + "unknown"
+
 type Handler struct {
 	logLevel           int
 	ctx                context.Context
 	cancel             context.CancelFunc
 	errChan            chan error
 	transactionHandler map[string]func(conn *qpConn.Connection, stream *qpStream.Stream, transactionName string, data []byte) error
+
+	mutex   sync.Mutex
+	workers *workers
+	metrics metrics.Metrics
 }
 
 func New(loglevel int, ctx context.Context, cancel context.CancelFunc) *Handler {
@@ -32,19 +45,46 @@ func New(loglevel int, ctx context.Context, cancel context.CancelFunc) *Handler
 		cancel:             cancel,
 		errChan:            nil,
 		transactionHandler: transactionHandler,
+		workers:            newWorkers(Concurrency{}),
+		metrics:            metrics.Noop{},
 	}
 }
 
 func (h *Handler) RouteTransaction(conn *qpConn.Connection) error {
+	workers := h.getWorkers()
+	connQueue := newSemaphore(workers.concurrency.MaxQueue)
+	connRunning := newSemaphore(workers.concurrency.MaxTransactionsPerConnection)
+	connCtx := conn.Conn.Context()
+	queues := []semaphore{workers.queue, connQueue}
 	for {
+		// Wait for room in the queue before accepting a stream, so the peer is pushed back while the queue is full.
+		// While a file is received in ranges, the stream is accepted without room in the queue,
+		// because the transaction receiving the file may hold the limits that the queued transactions wait for.
+		queued, err := h.enqueue(queues, connCtx, conn.ReceivingRanges())
+		if err != nil {
+			log.Println("quics-protocol: ", err)
+			return err
+		}
 		stream, err := h.RecvTransaction(conn)
 		if err != nil {
+			if queued {
+				dequeue(queues)
+			}
 			log.Println("quics-protocol: ", err)
 			return err
 		}
 		go func() {
 			defer stream.Close()
-			transaction, err := qpConn.RecvTransactionHandshake(stream)
+			dequeued := !queued
+			dequeueOnce := func() {
+				if !dequeued {
+					dequeued = true
+					dequeue(queues)
+				}
+			}
+			defer dequeueOnce()
+
+			transaction, err := conn.AcceptTransaction(stream)
 			if err != nil {
 				log.Println("quics-protocol: ", err)
 				return
@@ -52,37 +92,175 @@ func (h *Handler) RouteTransaction(conn *qpConn.Connection) error {
 			if h.logLevel <= qpLog.INFO {
 				log.Println("quics-protocol: ", "transaction accepted")
 			}
-
-			if h.transactionHandler[transaction.TransactionName] == nil {
-				log.Println("quics-protocol: ", "handler for transaction ", transaction.TransactionName, " is not set. Use 'default' handler.")
-				err = h.transactionHandler["default"](conn, stream, transaction.TransactionName, transaction.TransactionID)
+			if strings.HasPrefix(transaction.TransactionName, qpConn.ReservedTransactionPrefix) && !qpConn.IsReservedTransactionName(transaction.TransactionName) {
+				// Unknown reserved names are rejected, so they cannot be used to escape the limits.
+				log.Println("quics-protocol: ", "unknown reserved transaction ", transaction.TransactionName)
+				h.refuse(stream, transaction.TransactionName, qpErr.UnknownTransactionCode)
+				return
+			}
+			err = conn.CheckAuthentication(transaction.TransactionName)
+			if err != nil {
+				log.Println("quics-protocol: ", err)
+				h.refuse(stream, transaction.TransactionName, qpErr.UnauthenticatedCode)
+				return
+			}
+			if strings.HasPrefix(transaction.TransactionName, qpConn.ReservedTransactionPrefix) &&
+				transaction.TransactionName != qpConn.ControlTransactionName {
+				// The control stream lasts as long as the connection, and only one is accepted for each connection.
+				done, err := conn.BeginPreAuth(stream)
 				if err != nil {
-					log.Println("quics-protocol: err from transactionHandler [", transaction.TransactionName, "] : ", err)
-					if h.errChan != nil {
-						h.errChan <- err
-					}
-					err = stream.SendError(err.Error())
-					if err != nil {
-						log.Println("quics-protocol: ", err)
-					}
+					log.Println("quics-protocol: ", err)
+					h.refuse(stream, transaction.TransactionName, qpErr.LimitExceededCode)
+					return
 				}
-			} else {
-				err = h.transactionHandler[transaction.TransactionName](conn, stream, transaction.TransactionName, transaction.TransactionID)
+				defer done()
+			}
+
+			if !queued && transaction.TransactionName != qpConn.FileRangeTransactionName {
+				// The transaction is accepted beyond the queue, so it waits for room in the queue like the others.
+				_, err = h.enqueue(queues, connCtx, nil)
 				if err != nil {
-					log.Println("quics-protocol: err from transactionHandler [", transaction.TransactionName, "] : ", err)
-					if h.errChan != nil {
-						h.errChan <- err
-					}
-					err = stream.SendError(err.Error())
+					log.Println("quics-protocol: ", err)
+					return
+				}
+				dequeued = false
+			}
+			if !strings.HasPrefix(transaction.TransactionName, qpConn.ReservedTransactionPrefix) {
+				// The most specific limit is acquired first, so a transaction waiting for it does not hold the others.
+				for _, sem := range []semaphore{workers.name(transaction.TransactionName), connRunning, workers.running} {
+					err = sem.acquire(h.ctx, connCtx)
 					if err != nil {
 						log.Println("quics-protocol: ", err)
+						return
 					}
+					defer sem.release()
 				}
 			}
+			dequeueOnce()
+			defer conn.TrackTransaction(transaction.TransactionName)()
+			start := time.Now()
+			err = h.handleTransaction(conn, stream, transaction.TransactionName, transaction.TransactionID)
+			if transaction.TransactionName == qpConn.ControlTransactionName {
+				// The control stream lasts as long as the connection, so it is not reported as a handled transaction.
+				return
+			}
+			outcome := metrics.OutcomeOK
+			if err != nil {
+				outcome = metrics.OutcomeError
+			}
+			h.getMetrics().TransactionHandled(h.MetricName(transaction.TransactionName), outcome, time.Since(start))
 		}()
 	}
 }
 
+// enqueue acquires room in all of the queues, and reports whether it is acquired.
+// If the bypass channel is closed before, no room is held and it reports false.
+func (h *Handler) enqueue(queues []semaphore, connCtx context.Context, bypass <-chan struct{}) (bool, error) {
+	for i, queue := range queues {
+		acquired, err := queue.acquireUnless(h.ctx, connCtx, bypass)
+		if err != nil || !acquired {
+			dequeue(queues[:i])
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func dequeue(queues []semaphore) {
+	for _, queue := range queues {
+		queue.release()
+	}
+}
+
+// MetricName returns the transaction name reported to the metrics for a transaction received from the peer.
+// It is the name of the handler of the transaction, so the peer cannot make the metrics grow without bound with arbitrary names:
+// a transaction without its own handler is reported as "default",
+// and a reserved transaction that is not known is reported as "quics-protocol:unknown".
+// This method is used internally by quics-protocol.
+// So, you may don't need to use it directly.
+func (h *Handler) MetricName(transactionName string) string {
+	if strings.HasPrefix(transactionName, qpConn.ReservedTransactionPrefix) {
+		if qpConn.IsReservedTransactionName(transactionName) {
+			return transactionName
+		}
+		return unknownReservedMetricName
+	}
+	if h.transactionHandler[transactionName] == nil {
+		return "default"
+	}
+	return transactionName
+}
+
+// refuse resets the stream of a transaction that is not handled with the error code.
+func (h *Handler) refuse(stream *qpStream.Stream, transactionName string, code uint64) {
+	m := h.getMetrics()
+	m.TransactionHandled(h.MetricName(transactionName), metrics.OutcomeRefused, 0)
+	m.Error(code)
+	stream.Stream.CancelRead(quic.StreamErrorCode(code))
+	stream.Stream.CancelWrite(quic.StreamErrorCode(code))
+}
+
+// handleTransaction calls the handler of the transaction, and returns the error of the handler.
+func (h *Handler) handleTransaction(conn *qpConn.Connection, stream *qpStream.Stream, transactionName string, transactionID []byte) error {
+	if transactionName == qpConn.ControlTransactionName {
+		err := conn.RecvControl(stream)
+		if err != nil {
+			log.Println("quics-protocol: ", err)
+		}
+		return err
+	}
+	if transactionName == qpConn.HelloTransactionName {
+		err := conn.RecvHello(stream)
+		if err != nil {
+			log.Println("quics-protocol: ", err)
+		}
+		return err
+	}
+	if transactionName == qpConn.AuthTransactionName {
+		err := conn.RecvAuthentication(stream)
+		if err != nil {
+			log.Println("quics-protocol: ", err)
+		}
+		return err
+	}
+	if transactionName == qpConn.EnrollTransactionName {
+		err := conn.RecvEnrollment(stream)
+		if err != nil {
+			log.Println("quics-protocol: ", err)
+		}
+		return err
+	}
+	if transactionName == qpConn.FileRangeTransactionName {
+		err := conn.RecvFileRange(stream)
+		if err != nil {
+			log.Println("quics-protocol: ", err)
+			sendErr := stream.SendError(err.Error())
+			if sendErr != nil {
+				log.Println("quics-protocol: ", sendErr)
+			}
+		}
+		return err
+	}
+
+	handler := h.transactionHandler[transactionName]
+	if handler == nil {
+		log.Println("quics-protocol: ", "handler for transaction ", transactionName, " is not set. Use 'default' handler.")
+		handler = h.transactionHandler["default"]
+	}
+	err := handler(conn, stream, transactionName, transactionID)
+	if err != nil {
+		log.Println("quics-protocol: err from transactionHandler [", transactionName, "] : ", err)
+		if h.errChan != nil {
+			h.errChan <- err
+		}
+		sendErr := stream.SendError(err.Error())
+		if sendErr != nil {
+			log.Println("quics-protocol: ", sendErr)
+		}
+	}
+	return err
+}
+
 func (h *Handler) RecvTransaction(conn *qpConn.Connection) (*qpStream.Stream, error) {
 	stream, err := conn.Conn.AcceptStream(h.ctx)
 	if err != nil {
@@ -92,7 +270,7 @@ func (h *Handler) RecvTransaction(conn *qpConn.Connection) (*qpStream.Stream, er
 	if h.logLevel <= qpLog.INFO {
 		log.Println("quics-protocol: ", "stream accepted")
 	}
-	newStream, err := qpStream.New(h.logLevel, stream)
+	newStream, err := conn.NewStream(stream)
 	if err != nil {
 		log.Println("quics-protocol: ", err)
 		newStream.Close()
@@ -102,6 +280,19 @@ func (h *Handler) RecvTransaction(conn *qpConn.Connection) (*qpStream.Stream, er
 	return newStream, nil
 }
 
+// SetMetrics sets the metrics that the handled transactions are reported to.
+func (h *Handler) SetMetrics(m metrics.Metrics) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.metrics = m
+}
+
+func (h *Handler) getMetrics() metrics.Metrics {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.metrics
+}
+
 func (h *Handler) GetErrChan() chan error {
 	h.errChan = make(chan error)
 	return h.errChan
@@ -111,6 +302,9 @@ func (h *Handler) AddTransactionHandleFunc(transactionName string, handler func(
 	if transactionName == "default" {
 		return errors.New("quics-protocol: 'default' is reserved transaction name")
 	}
+	if strings.HasPrefix(transactionName, qpConn.ReservedTransactionPrefix) {
+		return errors.New("quics-protocol: '" + qpConn.ReservedTransactionPrefix + "' is reserved transaction name prefix")
+	}
 	h.transactionHandler[transactionName] = handler
 	return nil
 }
@@ -0,0 +1,48 @@
+package metrics
+
+import "time"
+
+// Outcomes of the transactions reported to Metrics.
+const (
+	// OutcomeOK means the handler of the transaction returned no error.
+	OutcomeOK = "ok"
+	// OutcomeError means the handler of the transaction returned an error.
+	OutcomeError = "error"
+	// OutcomeRefused means the transaction was refused before it was handled, like an unauthenticated one.
+	OutcomeRefused = "refused"
+)
+
+// Metrics collects the metrics reported by quics-protocol.
+// The methods are called from multiple goroutines, so they must be safe for concurrent use.
+type Metrics interface {
+	// ConnectionAccepted is called when a connection is accepted by a server and admitted.
+	ConnectionAccepted()
+	// ConnectionClosed is called when a connection accepted by a server is closed.
+	ConnectionClosed()
+	// TransactionHandled is called when a transaction received from the peer is handled or refused,
+	// with the latency of its handler. The transaction name is the name of its handler, or "default" if it has no handler,
+	// so the names are bounded. Unknown reserved transactions are reported as "quics-protocol:unknown".
+	TransactionHandled(transactionName string, outcome string, latency time.Duration)
+	// BytesSent is called with the bytes sent for a request type, like "BMESSAGE" or "FILE".
+	BytesSent(requestType string, bytes int64)
+	// BytesReceived is called with the bytes received for a request type, like "BMESSAGE" or "FILE".
+	// The request types unknown to this version are reported as "unknown".
+	BytesReceived(requestType string, bytes int64)
+	// Error is called with the error code when a stream is reset or a connection is closed with one of the error codes.
+	Error(code uint64)
+}
+
+// Noop is a Metrics that discards all the metrics. It is used by default.
+type Noop struct{}
+
+func (Noop) ConnectionAccepted() {}
+
+func (Noop) ConnectionClosed() {}
+
+func (Noop) TransactionHandled(transactionName string, outcome string, latency time.Duration) {}
+
+func (Noop) BytesSent(requestType string, bytes int64) {}
+
+func (Noop) BytesReceived(requestType string, bytes int64) {}
+
+func (Noop) Error(code uint64) {}
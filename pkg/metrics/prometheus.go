@@ -0,0 +1,188 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultBuckets are the upper bounds in seconds of the buckets of the handler latency histogram used by default.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Prometheus is a Metrics that keeps the metrics in memory and exposes them in the Prometheus text exposition format.
+// Serve them with Handler, for example on "/metrics".
+type Prometheus struct {
+	mutex               sync.Mutex
+	buckets             []float64
+	connectionsAccepted uint64
+	connectionsClosed   uint64
+	transactions        map[[2]string]uint64
+	latencies           map[string]*histogram
+	bytes               map[[2]string]int64
+	errors              map[uint64]uint64
+}
+
+// histogram is a cumulative histogram of the latencies of a transaction name.
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// NewPrometheus creates a new Prometheus metrics with the upper bounds in seconds of the buckets of the handler latency histogram.
+// If no buckets are given, DefaultBuckets are used.
+func NewPrometheus(buckets ...float64) *Prometheus {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	buckets = append([]float64{}, buckets...)
+	sort.Float64s(buckets)
+	return &Prometheus{
+		buckets:      buckets,
+		transactions: make(map[[2]string]uint64),
+		latencies:    make(map[string]*histogram),
+		bytes:        make(map[[2]string]int64),
+		errors:       make(map[uint64]uint64),
+	}
+}
+
+func (p *Prometheus) ConnectionAccepted() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.connectionsAccepted++
+}
+
+func (p *Prometheus) ConnectionClosed() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.connectionsClosed++
+}
+
+func (p *Prometheus) TransactionHandled(transactionName string, outcome string, latency time.Duration) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.transactions[[2]string{transactionName, outcome}]++
+	h := p.latencies[transactionName]
+	if h == nil {
+		h = &histogram{counts: make([]uint64, len(p.buckets))}
+		p.latencies[transactionName] = h
+	}
+	seconds := latency.Seconds()
+	for i, bound := range p.buckets {
+		if seconds <= bound {
+			h.counts[i]++
+		}
+	}
+	h.sum += seconds
+	h.count++
+}
+
+func (p *Prometheus) BytesSent(requestType string, bytes int64) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.bytes[[2]string{"sent", requestType}] += bytes
+}
+
+func (p *Prometheus) BytesReceived(requestType string, bytes int64) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.bytes[[2]string{"received", requestType}] += bytes
+}
+
+func (p *Prometheus) Error(code uint64) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.errors[code]++
+}
+
+// Handler returns the http.Handler that serves the metrics in the Prometheus text exposition format.
+func (p *Prometheus) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		p.WriteText(w)
+	})
+}
+
+// WriteText writes the metrics in the Prometheus text exposition format.
+// The series of each metric are sorted by their labels.
+func (p *Prometheus) WriteText(w io.Writer) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	out := bufio.NewWriter(w)
+
+	writeHeader(out, "quics_connections_accepted_total", "counter", "Connections accepted by the server.")
+	fmt.Fprintf(out, "quics_connections_accepted_total %d\n", p.connectionsAccepted)
+	writeHeader(out, "quics_connections_closed_total", "counter", "Connections accepted by the server and closed.")
+	fmt.Fprintf(out, "quics_connections_closed_total %d\n", p.connectionsClosed)
+
+	writeHeader(out, "quics_transactions_total", "counter", "Transactions handled by name and outcome.")
+	for _, key := range sortedKeys(p.transactions) {
+		fmt.Fprintf(out, "quics_transactions_total{name=%s,outcome=%s} %d\n", quote(key[0]), quote(key[1]), p.transactions[key])
+	}
+
+	writeHeader(out, "quics_handler_duration_seconds", "histogram", "Latency of the transaction handlers by name.")
+	names := make([]string, 0, len(p.latencies))
+	for name := range p.latencies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		h := p.latencies[name]
+		for i, bound := range p.buckets {
+			fmt.Fprintf(out, "quics_handler_duration_seconds_bucket{name=%s,le=%s} %d\n", quote(name), quote(formatFloat(bound)), h.counts[i])
+		}
+		fmt.Fprintf(out, "quics_handler_duration_seconds_bucket{name=%s,le=\"+Inf\"} %d\n", quote(name), h.count)
+		fmt.Fprintf(out, "quics_handler_duration_seconds_sum{name=%s} %s\n", quote(name), formatFloat(h.sum))
+		fmt.Fprintf(out, "quics_handler_duration_seconds_count{name=%s} %d\n", quote(name), h.count)
+	}
+
+	writeHeader(out, "quics_bytes_total", "counter", "Bytes sent and received by request type.")
+	for _, key := range sortedKeys(p.bytes) {
+		fmt.Fprintf(out, "quics_bytes_total{direction=%s,request_type=%s} %d\n", quote(key[0]), quote(key[1]), p.bytes[key])
+	}
+
+	writeHeader(out, "quics_errors_total", "counter", "Streams reset and connections closed by error code.")
+	codes := make([]uint64, 0, len(p.errors))
+	for code := range p.errors {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i] < codes[j] })
+	for _, code := range codes {
+		fmt.Fprintf(out, "quics_errors_total{code=\"0x%x\"} %d\n", code, p.errors[code])
+	}
+	return out.Flush()
+}
+
+func writeHeader(out io.Writer, name string, metricType string, help string) {
+	fmt.Fprintf(out, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+}
+
+func sortedKeys[V any](m map[[2]string]V) [][2]string {
+	keys := make([][2]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i][0] != keys[j][0] {
+			return keys[i][0] < keys[j][0]
+		}
+		return keys[i][1] < keys[j][1]
+	})
+	return keys
+}
+
+// quote quotes a label value, escaping backslashes, double quotes and line feeds.
+func quote(value string) string {
+	value = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+	return `"` + value + `"`
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
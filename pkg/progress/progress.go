@@ -0,0 +1,238 @@
+package progress
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// smoothing is the weight of the latest interval in the moving average of the throughput.
+const smoothing = 0.3
+
+// Progress is a snapshot of a transfer passed to the progress callback.
+type Progress struct {
+	// Name of the current entry. It is empty for bytes messages.
+	Name string
+	// Bytes done and total bytes of the current entry.
+	EntryDone  int64
+	EntryTotal int64
+	// Bytes done and total bytes of all entries.
+	// For directory transfers, the total is known from the start.
+	// Otherwise, it is the sum of the entries started so far.
+	Done  int64
+	Total int64
+	// Number of finished entries and total number of entries.
+	EntriesDone  int
+	EntriesTotal int
+	// Throughput is a moving average in bytes per second.
+	Throughput float64
+	// ETA is the estimated time to finish all entries. It is -1 when it is unknown.
+	ETA time.Duration
+	// Finished is true when the current entry is finished.
+	Finished bool
+}
+
+// Tracker tracks the progress of transfers through a stream and reports it to the callback at the interval.
+// The callback is always called when an entry is finished, so the last report of each entry is complete.
+// The callback is called in the goroutine sending or receiving the data, so it must not block for long.
+// All methods can be called on a nil Tracker and do nothing.
+type Tracker struct {
+	mutex    sync.Mutex
+	interval time.Duration
+	callback func(progress Progress)
+
+	name         string
+	entryDone    int64
+	entryTotal   int64
+	active       bool
+	done         int64
+	total        int64
+	entriesDone  int
+	entriesTotal int
+	batch        bool
+
+	throughput float64
+	lastReport time.Time
+	lastDone   int64
+}
+
+// New creates a new tracker reporting to the callback at the interval.
+func New(interval time.Duration, callback func(progress Progress)) *Tracker {
+	return &Tracker{
+		interval: interval,
+		callback: callback,
+	}
+}
+
+// BeginBatch starts a batch of entries with known totals, like a directory transfer.
+// While the batch is running, the aggregate totals are not changed by each entry.
+func (t *Tracker) BeginBatch(entries int, total int64) {
+	if t == nil {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.batch = true
+	t.done = 0
+	t.total = total
+	t.entriesDone = 0
+	t.entriesTotal = entries
+	t.resetThroughput()
+}
+
+// EndBatch finishes the batch started by BeginBatch.
+func (t *Tracker) EndBatch() {
+	if t == nil {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.batch = false
+}
+
+// Begin starts a new entry with the size.
+// An entry with size 0 is finished immediately.
+func (t *Tracker) Begin(name string, size int64) {
+	if t == nil {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if !t.batch {
+		if !t.active && t.entriesDone == t.entriesTotal {
+			// Nothing is in progress, so start the aggregate totals again.
+			t.done = 0
+			t.total = 0
+			t.entriesDone = 0
+			t.entriesTotal = 0
+			t.resetThroughput()
+		}
+		t.total += size
+		t.entriesTotal++
+	}
+	t.name = name
+	t.entryDone = 0
+	t.entryTotal = size
+	t.active = true
+	if size <= 0 {
+		t.finish()
+	}
+}
+
+// Add adds n bytes to the current entry.
+// The entry is finished when all of its bytes are added.
+func (t *Tracker) Add(n int64) {
+	if t == nil || n <= 0 {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if !t.active {
+		return
+	}
+	if t.entryDone+n > t.entryTotal {
+		n = t.entryTotal - t.entryDone
+	}
+	t.entryDone += n
+	t.done += n
+	if t.entryDone >= t.entryTotal {
+		t.finish()
+		return
+	}
+	if time.Since(t.lastReport) >= t.interval {
+		t.report(false)
+	}
+}
+
+// Reader returns a reader that adds the read bytes to the current entry.
+func (t *Tracker) Reader(r io.Reader) io.Reader {
+	if t == nil {
+		return r
+	}
+	return &reader{
+		r:       r,
+		tracker: t,
+	}
+}
+
+// Writer returns a writer that adds the written bytes to the current entry.
+func (t *Tracker) Writer(w io.Writer) io.Writer {
+	if t == nil {
+		return w
+	}
+	return &writer{
+		w:       w,
+		tracker: t,
+	}
+}
+
+func (t *Tracker) finish() {
+	t.active = false
+	t.entriesDone++
+	t.report(true)
+}
+
+func (t *Tracker) resetThroughput() {
+	t.throughput = 0
+	t.lastReport = time.Now()
+	t.lastDone = 0
+}
+
+// report calls the callback with the current progress. The mutex must be held.
+func (t *Tracker) report(finished bool) {
+	now := time.Now()
+	if elapsed := now.Sub(t.lastReport).Seconds(); elapsed > 0 {
+		rate := float64(t.done-t.lastDone) / elapsed
+		if t.throughput == 0 {
+			t.throughput = rate
+		} else {
+			t.throughput = smoothing*rate + (1-smoothing)*t.throughput
+		}
+	}
+	t.lastReport = now
+	t.lastDone = t.done
+
+	eta := time.Duration(-1)
+	if t.done >= t.total {
+		eta = 0
+	} else if t.throughput > 0 {
+		eta = time.Duration(float64(t.total-t.done) / t.throughput * float64(time.Second))
+	}
+
+	if t.callback != nil {
+		t.callback(Progress{
+			Name:         t.name,
+			EntryDone:    t.entryDone,
+			EntryTotal:   t.entryTotal,
+			Done:         t.done,
+			Total:        t.total,
+			EntriesDone:  t.entriesDone,
+			EntriesTotal: t.entriesTotal,
+			Throughput:   t.throughput,
+			ETA:          eta,
+			Finished:     finished,
+		})
+	}
+}
+
+type reader struct {
+	r       io.Reader
+	tracker *Tracker
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.tracker.Add(int64(n))
+	return n, err
+}
+
+type writer struct {
+	w       io.Writer
+	tracker *Tracker
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.tracker.Add(int64(n))
+	return n, err
+}
@@ -0,0 +1,204 @@
+package ratelimit
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// maxChunkSize is the maximum size of data passed through a limiter at once.
+// Smaller chunks make the transfer rate smoother.
+const maxChunkSize = 32 * 1024
+
+// Limiter is a token bucket rate limiter in bytes per second.
+// A limiter can have a parent limiter, so data passed through the limiter is also limited by the parent.
+// This is used to limit the rate of a transaction, its connection and the whole quics-protocol instance at the same time.
+// The limit can be changed at any time. A limit of 0 or less means unlimited.
+type Limiter struct {
+	mutex  sync.Mutex
+	parent *Limiter
+	limit  int64
+	tokens float64
+	last   time.Time
+	// changed is closed and replaced when the limit is changed, so waiting callers recompute their delay.
+	changed chan struct{}
+}
+
+// New creates a new limiter with the limit in bytes per second and the parent limiter.
+// The parent can be nil.
+func New(limit int64, parent *Limiter) *Limiter {
+	return &Limiter{
+		parent:  parent,
+		limit:   limit,
+		tokens:  float64(burst(limit)),
+		last:    time.Now(),
+		changed: make(chan struct{}),
+	}
+}
+
+// SetParent changes the parent limiter. The parent can be nil.
+func (l *Limiter) SetParent(parent *Limiter) {
+	if l == nil {
+		return
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.parent = parent
+}
+
+// SetLimit changes the limit in bytes per second. A limit of 0 or less means unlimited.
+func (l *Limiter) SetLimit(limit int64) {
+	if l == nil {
+		return
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.limit = limit
+	l.tokens = float64(burst(limit))
+	l.last = time.Now()
+	l.notify()
+}
+
+// notify wakes up the callers waiting for the limiter. The mutex must be held.
+func (l *Limiter) notify() {
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
+// Limit returns the limit in bytes per second.
+func (l *Limiter) Limit() int64 {
+	if l == nil {
+		return 0
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.limit
+}
+
+// WaitN blocks until n bytes are allowed by the limiter and all of its parents.
+// It returns the error of the context if the context is done before.
+// If the limit is changed while waiting, the wait is recomputed with the new limit.
+func (l *Limiter) WaitN(ctx context.Context, n int) error {
+	limiter := l
+	for limiter != nil {
+		delay, changed, parent := limiter.reserve(n)
+		if delay <= 0 {
+			limiter = parent
+			continue
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+			limiter = parent
+		case <-changed:
+			// The tokens are refilled by the change, so the bytes are reserved again with the new limit.
+			timer.Stop()
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// reserve takes n tokens from the bucket and returns how long to wait until the tokens are available,
+// the channel closed when the limiter is changed and the parent limiter.
+// The bucket can go into debt, so data larger than the bucket size is also allowed after waiting.
+func (l *Limiter) reserve(n int) (time.Duration, chan struct{}, *Limiter) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.limit <= 0 {
+		return 0, l.changed, l.parent
+	}
+
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * float64(l.limit)
+	l.last = now
+	if capacity := float64(burst(l.limit)); l.tokens > capacity {
+		l.tokens = capacity
+	}
+	l.tokens -= float64(n)
+	if l.tokens >= 0 {
+		return 0, l.changed, l.parent
+	}
+	return time.Duration(-l.tokens / float64(l.limit) * float64(time.Second)), l.changed, l.parent
+}
+
+// burst returns the size of the bucket, which is a quarter of a second of data.
+func burst(limit int64) int64 {
+	if limit <= 0 {
+		return 0
+	}
+	if limit < 4 {
+		return 1
+	}
+	return limit / 4
+}
+
+// NewReader returns a reader whose read data is limited by the limiter.
+// Waiting for the limiter stops with the error of the context when the context is done.
+func NewReader(ctx context.Context, r io.Reader, limiter *Limiter) io.Reader {
+	return &reader{
+		ctx:     ctx,
+		r:       r,
+		limiter: limiter,
+	}
+}
+
+type reader struct {
+	ctx     context.Context
+	r       io.Reader
+	limiter *Limiter
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	if len(p) > maxChunkSize {
+		p = p[:maxChunkSize]
+	}
+	n, err := r.r.Read(p)
+	if n > 0 {
+		waitErr := r.limiter.WaitN(r.ctx, n)
+		if err == nil {
+			err = waitErr
+		}
+	}
+	return n, err
+}
+
+// NewWriter returns a writer whose written data is limited by the limiter.
+// Waiting for the limiter stops with the error of the context when the context is done.
+func NewWriter(ctx context.Context, w io.Writer, limiter *Limiter) io.Writer {
+	return &writer{
+		ctx:     ctx,
+		w:       w,
+		limiter: limiter,
+	}
+}
+
+type writer struct {
+	ctx     context.Context
+	w       io.Writer
+	limiter *Limiter
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		chunk := p
+		if len(chunk) > maxChunkSize {
+			chunk = chunk[:maxChunkSize]
+		}
+		err := w.limiter.WaitN(w.ctx, len(chunk))
+		if err != nil {
+			return written, err
+		}
+		n, err := w.w.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
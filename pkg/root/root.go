@@ -0,0 +1,231 @@
+package root
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/quic-s/quics-protocol/pkg/types/fileinfo"
+)
+
+// DefaultUmask masks the setuid, setgid and sticky bits of received files.
+const DefaultUmask = os.ModeSetuid | os.ModeSetgid | os.ModeSticky
+
+// umaskBits are the mode bits that can be masked by the umask.
+const umaskBits = os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky
+
+var (
+	// ErrPathEscape is returned when a name refers to a path outside of the root.
+	ErrPathEscape = errors.New("path is outside of the root")
+	// ErrInvalidName is returned when a name is not valid on this platform.
+	ErrInvalidName = errors.New("name is not valid on this platform")
+)
+
+// Root confines writing received files to a base directory.
+// The names of received files come from the peer, so they are checked before any file is written.
+// Absolute paths, ".." and names invalid on this platform are rejected,
+// and symbolic links are never followed while resolving the path under the base directory.
+// The mode bits in the umask are cleared from received files.
+type Root struct {
+	dir   string
+	umask os.FileMode
+}
+
+// New creates a new root for the base directory. The directory is created if it does not exist.
+// The umask is cleared from the mode of received files, DefaultUmask is recommended.
+func New(dir string, umask os.FileMode) (*Root, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	err = os.MkdirAll(dir, 0700)
+	if err != nil {
+		return nil, err
+	}
+	return &Root{
+		dir:   dir,
+		umask: umask & umaskBits,
+	}, nil
+}
+
+// Dir returns the base directory.
+func (r *Root) Dir() string {
+	return r.dir
+}
+
+// Path checks the name and returns its path under the base directory.
+// The name is a relative path separated by slashes, like FileInfo.Name of directory transfers.
+// Symbolic links are not checked, so use WriteFile to write a file safely.
+func (r *Root) Path(name string) (string, error) {
+	components, err := splitName(name)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(append([]string{r.dir}, components...)...), nil
+}
+
+// WriteFile writes the file to the path of FileInfo.Name under the base directory through FileInfo.WriteFileWithOptions.
+// The parent directories are created without following symbolic links.
+// The mode bits in the umask are cleared before the file is written.
+func (r *Root) WriteFile(fileInfo *fileinfo.FileInfo, fileContent io.Reader, opts *fileinfo.WriteOptions) error {
+	dir, name, err := r.resolve(fileInfo.Name)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+
+	filePath := filepath.Join(dir.path, name)
+	if fileInfo.IsDir {
+		// A directory is opened to set its metadata, so it must not be a symbolic link.
+		stat, err := os.Lstat(filePath)
+		if err == nil && stat.Mode()&os.ModeSymlink != 0 {
+			return ErrPathEscape
+		}
+	}
+	return r.mask(fileInfo).WriteFileWithOptions(filePath, fileContent, opts)
+}
+
+// WriteHardlink writes the file as a hard link to FileInfo.HardlinkTarget under the base directory
+// through FileInfo.WriteHardlink.
+func (r *Root) WriteHardlink(fileInfo *fileinfo.FileInfo, opts *fileinfo.WriteOptions) error {
+	targetDir, targetName, err := r.resolve(fileInfo.HardlinkTarget)
+	if err != nil {
+		return err
+	}
+	defer targetDir.Close()
+	targetPath := filepath.Join(targetDir.path, targetName)
+	stat, err := os.Lstat(targetPath)
+	if err != nil {
+		return err
+	}
+	if !stat.Mode().IsRegular() {
+		return errors.New("hard link target is not a regular file: " + fileInfo.HardlinkTarget)
+	}
+
+	dir, name, err := r.resolve(fileInfo.Name)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return r.mask(fileInfo).WriteHardlink(targetPath, filepath.Join(dir.path, name), opts)
+}
+
+// Chtimes changes the access and modification time of the file of the name under the base directory.
+// It does not follow a symbolic link at the name.
+func (r *Root) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	dir, base, err := r.resolve(name)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	filePath := filepath.Join(dir.path, base)
+	stat, err := os.Lstat(filePath)
+	if err != nil {
+		return err
+	}
+	if stat.Mode()&os.ModeSymlink != 0 {
+		return nil
+	}
+	return os.Chtimes(filePath, atime, mtime)
+}
+
+// mask returns a copy of the file metadata with the mode bits in the umask cleared.
+func (r *Root) mask(fileInfo *fileinfo.FileInfo) *fileinfo.FileInfo {
+	masked := *fileInfo
+	masked.Mode &^= r.umask
+	return &masked
+}
+
+// resolve opens the parent directory of the name, creating the directories on the way without following symbolic links.
+// It returns the opened directory and the last component of the name.
+func (r *Root) resolve(name string) (*dirHandle, string, error) {
+	components, err := splitName(name)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(components) == 0 {
+		return nil, "", ErrInvalidName
+	}
+	dir, err := r.openDir(components[:len(components)-1])
+	if err != nil {
+		return nil, "", err
+	}
+	return dir, components[len(components)-1], nil
+}
+
+// splitName checks the name and splits it into path components.
+func splitName(name string) ([]string, error) {
+	if name == "" {
+		return nil, ErrInvalidName
+	}
+	if filepath.IsAbs(name) || filepath.VolumeName(name) != "" || strings.HasPrefix(name, "/") {
+		return nil, ErrPathEscape
+	}
+	if runtime.GOOS == "windows" {
+		name = strings.ReplaceAll(name, "\\", "/")
+		if strings.HasPrefix(name, "/") {
+			return nil, ErrPathEscape
+		}
+	}
+
+	components := []string{}
+	for _, component := range strings.Split(name, "/") {
+		if component == "" || component == "." {
+			continue
+		}
+		if component == ".." {
+			return nil, ErrPathEscape
+		}
+		if !validName(component) {
+			return nil, ErrInvalidName
+		}
+		components = append(components, component)
+	}
+	return components, nil
+}
+
+// windowsReserved are the file names reserved by Windows, with or without an extension.
+var windowsReserved = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// validName reports whether the path component is a valid file name on this platform.
+func validName(name string) bool {
+	if len(name) > 255 || strings.ContainsRune(name, 0) {
+		return false
+	}
+	if runtime.GOOS != "windows" {
+		return true
+	}
+
+	if strings.ContainsAny(name, `<>:"|?*`) || strings.HasSuffix(name, " ") || strings.HasSuffix(name, ".") {
+		return false
+	}
+	for _, c := range name {
+		if c < 0x20 {
+			return false
+		}
+	}
+	base, _, _ := strings.Cut(name, ".")
+	return !windowsReserved[strings.ToUpper(strings.TrimRight(base, " "))]
+}
+
+// dirHandle is a directory resolved under the base directory.
+// The path is used for the file operations in the directory, and it is valid until the handle is closed.
+type dirHandle struct {
+	path string
+	file *os.File
+}
+
+func (d *dirHandle) Close() error {
+	if d.file == nil {
+		return nil
+	}
+	return d.file.Close()
+}
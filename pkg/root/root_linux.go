@@ -0,0 +1,54 @@
+//go:build linux
+
+package root
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+)
+
+// openDir opens the directory of the components under the base directory with openat,
+// so a symbolic link or a concurrent rename cannot redirect the path outside of the base directory.
+// The returned path refers to the opened directory through /proc/self/fd if it is available.
+func (r *Root) openDir(components []string) (*dirHandle, error) {
+	fd, err := syscall.Open(r.dir, syscall.O_RDONLY|syscall.O_DIRECTORY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: r.dir, Err: err}
+	}
+	for _, component := range components {
+		next, err := openat(fd, component)
+		if err == syscall.ENOENT {
+			err = syscall.Mkdirat(fd, component, 0700)
+			if err != nil && err != syscall.EEXIST {
+				syscall.Close(fd)
+				return nil, &os.PathError{Op: "mkdirat", Path: component, Err: err}
+			}
+			next, err = openat(fd, component)
+		}
+		syscall.Close(fd)
+		if err == syscall.ELOOP || err == syscall.ENOTDIR {
+			return nil, ErrPathEscape
+		}
+		if err != nil {
+			return nil, &os.PathError{Op: "openat", Path: component, Err: err}
+		}
+		fd = next
+	}
+
+	file := os.NewFile(uintptr(fd), filepath.Join(append([]string{r.dir}, components...)...))
+	path := "/proc/self/fd/" + strconv.Itoa(fd)
+	if _, err := os.Stat(path); err != nil {
+		// /proc is not mounted, so use the checked path.
+		path = file.Name()
+	}
+	return &dirHandle{
+		path: path,
+		file: file,
+	}, nil
+}
+
+func openat(fd int, name string) (int, error) {
+	return syscall.Openat(fd, name, syscall.O_RDONLY|syscall.O_DIRECTORY|syscall.O_NOFOLLOW|syscall.O_CLOEXEC, 0)
+}
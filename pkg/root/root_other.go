@@ -0,0 +1,34 @@
+//go:build !linux
+
+package root
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// openDir creates the directory of the components under the base directory.
+// Each component is checked not to be a symbolic link, so the path cannot be redirected outside of the base directory.
+func (r *Root) openDir(components []string) (*dirHandle, error) {
+	path := r.dir
+	for _, component := range components {
+		path = filepath.Join(path, component)
+		stat, err := os.Lstat(path)
+		if os.IsNotExist(err) {
+			err = os.Mkdir(path, 0700)
+			if err != nil && !os.IsExist(err) {
+				return nil, err
+			}
+			stat, err = os.Lstat(path)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !stat.IsDir() {
+			return nil, ErrPathEscape
+		}
+	}
+	return &dirHandle{
+		path: path,
+	}, nil
+}
@@ -0,0 +1,305 @@
+package stream
+
+import (
+	"errors"
+	"io"
+	"log"
+	"os"
+
+	"github.com/google/uuid"
+	"github.com/quic-s/quics-protocol/pkg/chunk"
+	qpErr "github.com/quic-s/quics-protocol/pkg/error"
+	qpLog "github.com/quic-s/quics-protocol/pkg/log"
+	"github.com/quic-s/quics-protocol/pkg/types/fileinfo"
+	pb "github.com/quic-s/quics-protocol/proto/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// SendFileChunked sends a file split by content-defined chunking through the connection. The file path needs to be passed as a parameter.
+// The chunk manifest of the file is sent first, and only the chunks that the receiving side does not have are sent.
+// The metadata of the file is automatically sent to the receiving side.
+// This method must be used in pairs with RecvFileChunked.
+func (s *Stream) SendFileChunked(filePath string) error {
+	if s == nil || s.Stream == nil {
+		return errors.New("stream is nil")
+	}
+	file, err := os.Open(filePath)
+	if err != nil {
+		log.Println("quics-protocol: ", err)
+		return err
+	}
+	defer file.Close()
+
+	osFileInfo, err := file.Stat()
+	if err != nil {
+		log.Println("quics-protocol: ", err)
+		return err
+	}
+	if osFileInfo.IsDir() {
+		return errors.New("chunked transfer of a directory is not supported")
+	}
+	qpFileInfo, err := fileinfo.NewFromOSFileInfo(osFileInfo)
+	if err != nil {
+		log.Println("quics-protocol: ", err)
+		return err
+	}
+
+	refs := []chunk.Ref{}
+	chunker := chunk.NewChunker(io.LimitReader(file, qpFileInfo.Size))
+	for {
+		ref, _, err := chunker.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.Println("quics-protocol: ", err)
+			return err
+		}
+		refs = append(refs, ref)
+	}
+
+	requestId, err := uuid.New().MarshalBinary()
+	if err != nil {
+		return err
+	}
+	err = WriteHeader(s, pb.RequestType_FILE_CHUNKED, requestId, "")
+	if err != nil {
+		return err
+	}
+	err = WriteFileInfo(s, qpFileInfo)
+	if err != nil {
+		return err
+	}
+	err = WriteChunkManifest(s, refs)
+	if err != nil {
+		return err
+	}
+
+	header, err := ReadHeader(s)
+	if err != nil {
+		return err
+	}
+	if header.RequestType != pb.RequestType_CHUNK_REQUEST {
+		return errors.New("request type is not ChunkRequest")
+	}
+	indexes, err := ReadChunkRequest(s)
+	if err != nil {
+		return err
+	}
+	if s.logLevel <= qpLog.INFO {
+		log.Println("quics-protocol: ", "sending", len(indexes), "of", len(refs), "chunks")
+	}
+
+	buf := make([]byte, chunk.DefaultMaxSize)
+	for _, i := range indexes {
+		if i < 0 || i >= int64(len(refs)) {
+			return errors.New("requested chunk index is out of range")
+		}
+		ref := refs[i]
+		if int64(cap(buf)) < ref.Size {
+			buf = make([]byte, ref.Size)
+		}
+		data := buf[:ref.Size]
+		_, err := file.ReadAt(data, ref.Offset)
+		if err != nil {
+			log.Println("quics-protocol: ", err)
+			return err
+		}
+		if chunk.Sum(data) != ref.Hash {
+			s.reportError(qpErr.FileModifiedDuringTransferCode)
+			s.Stream.CancelWrite(qpErr.FileModifiedDuringTransferCode)
+			log.Println("quics-protocol: file is modified during transfer")
+			return qpErr.ErrFileModifiedDuringTransfer
+		}
+		err = WriteMessage(s, data)
+		if err != nil {
+			return err
+		}
+	}
+
+	afterFileInfo, err := file.Stat()
+	if err != nil {
+		log.Println("quics-protocol: ", err)
+		return err
+	}
+	if qpFileInfo.ModTime != afterFileInfo.ModTime() || qpFileInfo.Size != afterFileInfo.Size() || qpFileInfo.Mode != afterFileInfo.Mode() {
+		s.reportError(qpErr.FileModifiedDuringTransferCode)
+		s.Stream.CancelWrite(qpErr.FileModifiedDuringTransferCode)
+		log.Println("quics-protocol: file is modified during transfer")
+		return qpErr.ErrFileModifiedDuringTransfer
+	}
+	return nil
+}
+
+// RecvFileChunked receives a file sent by SendFileChunked and writes it to the file path.
+// Only the chunks missing in the store are requested. Received chunks are verified and kept in the store,
+// and the file is rebuilt from the store and written with its metadata through FileInfo.WriteFileWithInfo.
+// The file metadata is returned as a result.
+// This method must be used in pairs with SendFileChunked.
+func (s *Stream) RecvFileChunked(store chunk.Store, filePath string) (*fileinfo.FileInfo, error) {
+	if s == nil || s.Stream == nil {
+		return nil, errors.New("stream is nil")
+	}
+	if store == nil {
+		return nil, errors.New("chunk store is nil")
+	}
+
+	header, err := ReadHeader(s)
+	if err != nil {
+		return nil, err
+	}
+	if header.RequestType != pb.RequestType_FILE_CHUNKED {
+		return nil, errors.New("request type is not FileChunked")
+	}
+	fileInfo, err := ReadFileInfo(s)
+	if err != nil {
+		return nil, err
+	}
+	refs, err := ReadChunkManifest(s)
+	if err != nil {
+		return nil, err
+	}
+	size := int64(0)
+	for _, ref := range refs {
+		size += ref.Size
+	}
+	if size != fileInfo.Size {
+		return nil, errors.New("size of chunks is not equal with fileinfo.size")
+	}
+
+	// Request each missing chunk only once, even if it appears several times in the file.
+	indexes := []int64{}
+	requested := make(map[chunk.Hash]bool)
+	for i, ref := range refs {
+		if requested[ref.Hash] {
+			continue
+		}
+		has, err := store.Has(ref.Hash)
+		if err != nil {
+			return nil, err
+		}
+		if !has {
+			requested[ref.Hash] = true
+			indexes = append(indexes, int64(i))
+		}
+	}
+	if s.logLevel <= qpLog.INFO {
+		log.Println("quics-protocol: ", "requesting", len(indexes), "of", len(refs), "chunks")
+	}
+
+	requestId, err := uuid.New().MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	err = WriteHeader(s, pb.RequestType_CHUNK_REQUEST, requestId, "")
+	if err != nil {
+		return nil, err
+	}
+	err = WriteChunkRequest(s, indexes)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, i := range indexes {
+		data, err := ReadMessage(s)
+		if err != nil {
+			return nil, err
+		}
+		if int64(len(data)) != refs[i].Size {
+			return nil, errors.New("chunk size is not equal with manifest")
+		}
+		err = store.Put(refs[i].Hash, data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	hashes := make([]chunk.Hash, 0, len(refs))
+	for _, ref := range refs {
+		hashes = append(hashes, ref.Hash)
+	}
+	fileContent := chunk.NewReader(store, hashes)
+	defer fileContent.Close()
+
+	err = fileInfo.WriteFileWithInfo(filePath, fileContent)
+	if err != nil {
+		return nil, err
+	}
+	return fileInfo, nil
+}
+
+func WriteChunkManifest(s *Stream, refs []chunk.Ref) error {
+	manifest := &pb.ChunkManifest{
+		Chunks: make([]*pb.ChunkRef, 0, len(refs)),
+	}
+	for _, ref := range refs {
+		hash := ref.Hash
+		manifest.Chunks = append(manifest.Chunks, &pb.ChunkRef{
+			Hash: hash[:],
+			Size: ref.Size,
+		})
+	}
+	manifestOut, err := proto.Marshal(manifest)
+	if err != nil {
+		log.Println("quics-protocol: ", err)
+		return err
+	}
+	return WriteMessage(s, manifestOut)
+}
+
+func ReadChunkManifest(s *Stream) ([]chunk.Ref, error) {
+	manifestBuf, err := ReadMessage(s)
+	if err != nil {
+		return nil, err
+	}
+	manifest := &pb.ChunkManifest{}
+	err = proto.Unmarshal(manifestBuf, manifest)
+	if err != nil {
+		log.Println("quics-protocol: ", err)
+		return nil, err
+	}
+
+	refs := make([]chunk.Ref, 0, len(manifest.Chunks))
+	offset := int64(0)
+	for _, pbRef := range manifest.Chunks {
+		hash, err := chunk.HashFromBytes(pbRef.Hash)
+		if err != nil {
+			return nil, err
+		}
+		if pbRef.Size <= 0 {
+			return nil, errors.New("invalid chunk size")
+		}
+		refs = append(refs, chunk.Ref{
+			Hash:   hash,
+			Offset: offset,
+			Size:   pbRef.Size,
+		})
+		offset += pbRef.Size
+	}
+	return refs, nil
+}
+
+func WriteChunkRequest(s *Stream, indexes []int64) error {
+	requestOut, err := proto.Marshal(&pb.ChunkRequest{
+		Indexes: indexes,
+	})
+	if err != nil {
+		log.Println("quics-protocol: ", err)
+		return err
+	}
+	return WriteMessage(s, requestOut)
+}
+
+func ReadChunkRequest(s *Stream) ([]int64, error) {
+	requestBuf, err := ReadMessage(s)
+	if err != nil {
+		return nil, err
+	}
+	request := &pb.ChunkRequest{}
+	err = proto.Unmarshal(requestBuf, request)
+	if err != nil {
+		log.Println("quics-protocol: ", err)
+		return nil, err
+	}
+	return request.Indexes, nil
+}
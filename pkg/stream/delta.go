@@ -0,0 +1,366 @@
+package stream
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/google/uuid"
+	"github.com/quic-s/quics-protocol/pkg/delta"
+	qpErr "github.com/quic-s/quics-protocol/pkg/error"
+	qpLog "github.com/quic-s/quics-protocol/pkg/log"
+	"github.com/quic-s/quics-protocol/pkg/types/fileinfo"
+	pb "github.com/quic-s/quics-protocol/proto/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// SendFileDelta sends only the changed parts of a file through the connection. The file path needs to be passed as a parameter.
+// The receiving side sends the block signatures of its existing copy first,
+// and this method answers with the copy and literal instructions to rebuild the file from it.
+// The metadata of the file is automatically sent to the receiving side.
+// This method must be used in pairs with RecvFileDelta.
+func (s *Stream) SendFileDelta(filePath string) error {
+	if s == nil || s.Stream == nil {
+		return errors.New("stream is nil")
+	}
+	header, err := ReadHeader(s)
+	if err != nil {
+		return err
+	}
+	if header.RequestType != pb.RequestType_DELTA_SIGNATURE {
+		return errors.New("request type is not DeltaSignature")
+	}
+	sig, err := ReadDeltaSignature(s)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		log.Println("quics-protocol: ", err)
+		return err
+	}
+	defer file.Close()
+
+	osFileInfo, err := file.Stat()
+	if err != nil {
+		log.Println("quics-protocol: ", err)
+		return err
+	}
+	if osFileInfo.IsDir() {
+		return errors.New("delta transfer of a directory is not supported")
+	}
+	qpFileInfo, err := fileinfo.NewFromOSFileInfo(osFileInfo)
+	if err != nil {
+		log.Println("quics-protocol: ", err)
+		return err
+	}
+
+	requestId, err := uuid.New().MarshalBinary()
+	if err != nil {
+		return err
+	}
+	err = WriteHeader(s, pb.RequestType_FILE_DELTA, requestId, "")
+	if err != nil {
+		return err
+	}
+	err = WriteFileInfo(s, qpFileInfo)
+	if err != nil {
+		return err
+	}
+
+	hash := sha256.New()
+	copied, literal := int64(0), int64(0)
+	err = delta.Generate(sig, io.TeeReader(io.LimitReader(file, qpFileInfo.Size), hash), func(op *delta.Op) error {
+		if op.Type == delta.OpCopy {
+			copied += op.BlockCount
+		} else {
+			literal += int64(len(op.Data))
+		}
+		return WriteDeltaOp(s, op)
+	})
+	if err != nil {
+		log.Println("quics-protocol: ", err)
+		return err
+	}
+	if s.logLevel <= qpLog.INFO {
+		log.Println("quics-protocol: ", "delta generated, copied", copied, "blocks, literal", literal, "bytes")
+	}
+
+	afterFileInfo, err := file.Stat()
+	if err != nil {
+		log.Println("quics-protocol: ", err)
+		return err
+	}
+	if qpFileInfo.ModTime != afterFileInfo.ModTime() || qpFileInfo.Size != afterFileInfo.Size() || qpFileInfo.Mode != afterFileInfo.Mode() {
+		s.reportError(qpErr.FileModifiedDuringTransferCode)
+		s.Stream.CancelWrite(qpErr.FileModifiedDuringTransferCode)
+		log.Println("quics-protocol: file is modified during transfer")
+		return qpErr.ErrFileModifiedDuringTransfer
+	}
+
+	return WriteDeltaEnd(s, hash.Sum(nil))
+}
+
+// RecvFileDelta receives a file sent by SendFileDelta and writes it to the file path.
+// The block signatures of the existing file at the file path are sent first, so only the changed parts are transferred.
+// If the file does not exist, the whole file is transferred.
+// The rebuilt file is verified and then moved to the file path with its metadata through FileInfo.CommitFile.
+// The file metadata is returned as a result.
+// This method must be used in pairs with SendFileDelta.
+func (s *Stream) RecvFileDelta(filePath string) (*fileinfo.FileInfo, error) {
+	if s == nil || s.Stream == nil {
+		return nil, errors.New("stream is nil")
+	}
+
+	base, err := os.Open(filePath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if base != nil {
+		defer base.Close()
+	}
+
+	var sig *delta.Signature
+	if base != nil {
+		baseInfo, err := base.Stat()
+		if err != nil {
+			return nil, err
+		}
+		if baseInfo.IsDir() {
+			return nil, errors.New("delta transfer of a directory is not supported")
+		}
+		sig, err = delta.NewSignature(base, delta.BlockSizeFor(baseInfo.Size()))
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		sig, err = delta.NewSignature(bytes.NewReader(nil), delta.MinBlockSize)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	requestId, err := uuid.New().MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	err = WriteHeader(s, pb.RequestType_DELTA_SIGNATURE, requestId, "")
+	if err != nil {
+		return nil, err
+	}
+	err = WriteDeltaSignature(s, sig)
+	if err != nil {
+		return nil, err
+	}
+
+	header, err := ReadHeader(s)
+	if err != nil {
+		return nil, err
+	}
+	if header.RequestType != pb.RequestType_FILE_DELTA {
+		return nil, errors.New("request type is not FileDelta")
+	}
+	fileInfo, err := ReadFileInfo(s)
+	if err != nil {
+		return nil, err
+	}
+
+	// Rebuild the file next to the old one, so the old file stays intact until the new one is verified.
+	dir, name := filepath.Split(filePath)
+	if dir != "" {
+		err = os.MkdirAll(dir, 0700)
+		if err != nil {
+			return nil, err
+		}
+	}
+	tmp, err := os.CreateTemp(dir, "."+name+".delta-*")
+	if err != nil {
+		return nil, err
+	}
+	committed := false
+	defer func() {
+		tmp.Close()
+		if !committed {
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	var baseReader io.ReaderAt = bytes.NewReader(nil)
+	if base != nil {
+		baseReader = base
+	}
+	hash := sha256.New()
+	// The rebuilt file cannot be larger than the declared size, so a sender cannot fill the disk before the checksum is checked.
+	out := &deltaWriter{
+		s:    s,
+		w:    io.MultiWriter(tmp, hash),
+		size: fileInfo.Size,
+	}
+	for {
+		op, checksum, err := ReadDeltaOp(s)
+		if err != nil {
+			return nil, err
+		}
+		if op == nil {
+			if out.written != fileInfo.Size {
+				return nil, fmt.Errorf("delta size %d does not match the file size %d", out.written, fileInfo.Size)
+			}
+			if !bytes.Equal(checksum, hash.Sum(nil)) {
+				return nil, qpErr.ErrDeltaChecksumMismatch
+			}
+			break
+		}
+		err = delta.Apply(baseReader, sig.BlockSize, op, out)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = tmp.Close()
+	if err != nil {
+		return nil, err
+	}
+	if base != nil {
+		base.Close()
+	}
+	err = fileInfo.CommitFile(tmp.Name(), filePath, nil)
+	if err != nil {
+		return nil, err
+	}
+	committed = true
+	return fileInfo, nil
+}
+
+// deltaWriter writes the rebuilt file, and resets the stream when it exceeds the size of the file.
+type deltaWriter struct {
+	s       *Stream
+	w       io.Writer
+	size    int64
+	written int64
+}
+
+func (d *deltaWriter) Write(p []byte) (int, error) {
+	if d.written+int64(len(p)) > d.size {
+		return 0, d.s.exceedLimit("delta size", d.written+int64(len(p)), d.size)
+	}
+	n, err := d.w.Write(p)
+	d.written += int64(n)
+	return n, err
+}
+
+func WriteDeltaSignature(s *Stream, sig *delta.Signature) error {
+	pbSig := &pb.DeltaSignature{
+		BlockSize: int32(sig.BlockSize),
+		Blocks:    make([]*pb.BlockSignature, 0, len(sig.Blocks)),
+		FileSize:  sig.FileSize(),
+	}
+	for _, block := range sig.Blocks {
+		pbSig.Blocks = append(pbSig.Blocks, &pb.BlockSignature{
+			Weak:   block.Weak,
+			Strong: block.Strong,
+		})
+	}
+	sigOut, err := proto.Marshal(pbSig)
+	if err != nil {
+		log.Println("quics-protocol: ", err)
+		return err
+	}
+	return WriteMessage(s, sigOut)
+}
+
+func ReadDeltaSignature(s *Stream) (*delta.Signature, error) {
+	sigBuf, err := ReadMessage(s)
+	if err != nil {
+		return nil, err
+	}
+	pbSig := &pb.DeltaSignature{}
+	err = proto.Unmarshal(sigBuf, pbSig)
+	if err != nil {
+		log.Println("quics-protocol: ", err)
+		return nil, err
+	}
+	if pbSig.BlockSize <= 0 {
+		return nil, errors.New("invalid delta block size")
+	}
+
+	sig := &delta.Signature{
+		BlockSize: int(pbSig.BlockSize),
+		Blocks:    make([]delta.BlockSignature, 0, len(pbSig.Blocks)),
+	}
+	for _, block := range pbSig.Blocks {
+		sig.Blocks = append(sig.Blocks, delta.BlockSignature{
+			Weak:   block.Weak,
+			Strong: block.Strong,
+		})
+	}
+	sig.SetFileSize(pbSig.FileSize)
+	if s.logLevel <= qpLog.INFO {
+		log.Println("quics-protocol: ", "read delta signature with", len(sig.Blocks), "blocks of", sig.BlockSize, "bytes")
+	}
+	return sig, nil
+}
+
+func WriteDeltaOp(s *Stream, op *delta.Op) error {
+	pbOp := &pb.DeltaOp{}
+	switch op.Type {
+	case delta.OpCopy:
+		pbOp.Type = pb.DeltaOpType_DELTA_COPY
+		pbOp.BlockIndex = op.BlockIndex
+		pbOp.BlockCount = op.BlockCount
+	case delta.OpLiteral:
+		pbOp.Type = pb.DeltaOpType_DELTA_LITERAL
+		pbOp.Data = op.Data
+	default:
+		return errors.New("unknown delta instruction")
+	}
+	opOut, err := proto.Marshal(pbOp)
+	if err != nil {
+		log.Println("quics-protocol: ", err)
+		return err
+	}
+	return WriteMessage(s, opOut)
+}
+
+func WriteDeltaEnd(s *Stream, checksum []byte) error {
+	opOut, err := proto.Marshal(&pb.DeltaOp{
+		Type:     pb.DeltaOpType_DELTA_END,
+		Checksum: checksum,
+	})
+	if err != nil {
+		log.Println("quics-protocol: ", err)
+		return err
+	}
+	return WriteMessage(s, opOut)
+}
+
+// ReadDeltaOp reads a delta instruction.
+// When the end of the delta is reached, it returns a nil instruction with the checksum of the whole file.
+func ReadDeltaOp(s *Stream) (*delta.Op, []byte, error) {
+	opBuf, err := ReadMessage(s)
+	if err != nil {
+		return nil, nil, err
+	}
+	pbOp := &pb.DeltaOp{}
+	err = proto.Unmarshal(opBuf, pbOp)
+	if err != nil {
+		log.Println("quics-protocol: ", err)
+		return nil, nil, err
+	}
+
+	switch pbOp.Type {
+	case pb.DeltaOpType_DELTA_END:
+		return nil, pbOp.Checksum, nil
+	case pb.DeltaOpType_DELTA_COPY:
+		return &delta.Op{Type: delta.OpCopy, BlockIndex: pbOp.BlockIndex, BlockCount: pbOp.BlockCount}, nil, nil
+	case pb.DeltaOpType_DELTA_LITERAL:
+		return &delta.Op{Type: delta.OpLiteral, Data: pbOp.Data}, nil, nil
+	default:
+		return nil, nil, errors.New("unknown delta instruction")
+	}
+}
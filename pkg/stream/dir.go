@@ -0,0 +1,207 @@
+package stream
+
+import (
+	"errors"
+	"io/fs"
+	"log"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+	qpLog "github.com/quic-s/quics-protocol/pkg/log"
+	"github.com/quic-s/quics-protocol/pkg/root"
+	"github.com/quic-s/quics-protocol/pkg/types/fileinfo"
+	pb "github.com/quic-s/quics-protocol/proto/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// SendDir sends a directory tree through the connection. The directory path needs to be passed as a parameter.
+// Each directory and regular file in the tree is sent with its metadata and its path relative to the directory.
+// Symbolic links, hard links and special files are sent as they are only when they are enabled by SetMetadata.
+// Otherwise, symbolic links and special files are skipped and hard linked files are sent as separate files.
+// The number of entries and the total size are sent first, so the progress of the whole directory can be reported.
+// This method must be used in pairs with RecvDir.
+func (s *Stream) SendDir(dirPath string) error {
+	if s == nil || s.Stream == nil {
+		return errors.New("stream is nil")
+	}
+
+	type dirEntry struct {
+		path           string
+		name           string
+		hardlinkTarget string
+	}
+	entries := []dirEntry{}
+	totalSize := int64(0)
+	hardlinks := map[string]string{}
+	err := filepath.WalkDir(dirPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == dirPath {
+			return nil
+		}
+		symlink := d.Type()&fs.ModeSymlink != 0
+		special := d.Type()&(fs.ModeNamedPipe|fs.ModeDevice|fs.ModeCharDevice) != 0
+		if symlink && s.metadata&fileinfo.MetadataSymlink == 0 || special && s.metadata&fileinfo.MetadataSpecial == 0 ||
+			!d.IsDir() && !d.Type().IsRegular() && !symlink && !special {
+			if s.logLevel <= qpLog.INFO {
+				log.Println("quics-protocol: ", "skip", path, "which is not a regular file or directory")
+			}
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dirPath, path)
+		if err != nil {
+			return err
+		}
+		entry := dirEntry{
+			path: path,
+			name: filepath.ToSlash(rel),
+		}
+		if d.Type().IsRegular() {
+			key := ""
+			if s.metadata&fileinfo.MetadataHardlink != 0 {
+				key = fileinfo.HardlinkKey(info)
+			}
+			if target, ok := hardlinks[key]; ok {
+				entry.hardlinkTarget = target
+			} else {
+				if key != "" {
+					hardlinks[key] = entry.name
+				}
+				totalSize += info.Size()
+			}
+		}
+		entries = append(entries, entry)
+		return nil
+	})
+	if err != nil {
+		log.Println("quics-protocol: ", err)
+		return err
+	}
+
+	requestId, err := uuid.New().MarshalBinary()
+	if err != nil {
+		return err
+	}
+	err = WriteHeader(s, pb.RequestType_DIRECTORY, requestId, "")
+	if err != nil {
+		return err
+	}
+	manifestOut, err := proto.Marshal(&pb.DirManifest{
+		EntryCount: int64(len(entries)),
+		TotalSize:  totalSize,
+	})
+	if err != nil {
+		return err
+	}
+	err = WriteMessage(s, manifestOut)
+	if err != nil {
+		return err
+	}
+
+	s.tracker.BeginBatch(len(entries), totalSize)
+	defer s.tracker.EndBatch()
+	for _, entry := range entries {
+		err = writeFile(s, entry.path, entry.name, entry.hardlinkTarget)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RecvDir receives a directory tree sent by SendDir and writes it under the directory path.
+// Each entry is written with its metadata through FileInfo.WriteFileWithInfo.
+// Entries whose path would be outside of the directory path are rejected,
+// and the setuid, setgid and sticky bits of the entries are cleared.
+// The metadata of the received entries is returned as a result.
+// This method must be used in pairs with SendDir.
+func (s *Stream) RecvDir(dirPath string) ([]*fileinfo.FileInfo, error) {
+	return s.RecvDirWithOptions(dirPath, nil)
+}
+
+// RecvDirWithOptions receives a directory tree like RecvDir,
+// and writes each entry through FileInfo.WriteFileWithOptions with the options.
+// This method must be used in pairs with SendDir.
+func (s *Stream) RecvDirWithOptions(dirPath string, opts *fileinfo.WriteOptions) ([]*fileinfo.FileInfo, error) {
+	r, err := root.New(dirPath, root.DefaultUmask)
+	if err != nil {
+		return nil, err
+	}
+	return s.RecvDirInRoot(r, opts)
+}
+
+// RecvDirInRoot receives a directory tree like RecvDirWithOptions, and writes each entry through Root.WriteFile.
+// Entries outside of the root, including entries under a symbolic link, are rejected.
+// This method must be used in pairs with SendDir.
+func (s *Stream) RecvDirInRoot(r *root.Root, opts *fileinfo.WriteOptions) ([]*fileinfo.FileInfo, error) {
+	if s == nil || s.Stream == nil {
+		return nil, errors.New("stream is nil")
+	}
+	header, err := ReadHeader(s)
+	if err != nil {
+		return nil, err
+	}
+	if header.RequestType != pb.RequestType_DIRECTORY {
+		return nil, errors.New("request type is not Directory")
+	}
+	manifestBuf, err := ReadMessage(s)
+	if err != nil {
+		return nil, err
+	}
+	manifest := &pb.DirManifest{}
+	err = proto.Unmarshal(manifestBuf, manifest)
+	if err != nil {
+		return nil, err
+	}
+	if manifest.EntryCount < 0 {
+		return nil, errors.New("invalid directory manifest")
+	}
+
+	s.tracker.BeginBatch(int(manifest.EntryCount), manifest.TotalSize)
+	defer s.tracker.EndBatch()
+	fileInfos := []*fileinfo.FileInfo{}
+	dirs := []*fileinfo.FileInfo{}
+	files := map[string]bool{}
+	for i := int64(0); i < manifest.EntryCount; i++ {
+		fileInfo, fileContent, err := ReadFile(s)
+		if err != nil {
+			return nil, err
+		}
+
+		if fileInfo.HardlinkTarget != "" {
+			// Only an earlier entry can be linked, so existing files in the root cannot be exposed.
+			if !files[path.Clean(fileInfo.HardlinkTarget)] {
+				return nil, errors.New("hard link target is not an earlier entry: " + fileInfo.HardlinkTarget)
+			}
+			err = r.WriteHardlink(fileInfo, opts)
+		} else {
+			err = r.WriteFile(fileInfo, fileContent, opts)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if fileInfo.HasContent() {
+			files[path.Clean(fileInfo.Name)] = true
+		}
+		if fileInfo.IsDir {
+			dirs = append(dirs, fileInfo)
+		}
+		fileInfos = append(fileInfos, fileInfo)
+	}
+
+	// Writing entries changes the modification time of their directories, so set it again from the deepest directory.
+	for i := len(dirs) - 1; i >= 0; i-- {
+		err = r.Chtimes(dirs[i].Name, time.Now(), dirs[i].ModTime)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return fileInfos, nil
+}
@@ -0,0 +1,79 @@
+package stream
+
+import (
+	"log"
+
+	qpErr "github.com/quic-s/quics-protocol/pkg/error"
+)
+
+// Limits are the limits on the data received from the peer.
+// They are checked before any memory is allocated for the data. A limit of 0 or less means unlimited.
+type Limits struct {
+	// MaxMessageSize is the maximum size of a bytes message and of each message of the protocol in bytes.
+	MaxMessageSize int64
+	// MaxFileSize is the maximum size of a received file in bytes.
+	MaxFileSize int64
+	// MaxTransactionNameLength is the maximum length of a transaction name in bytes.
+	MaxTransactionNameLength int
+	// MaxRequestsPerTransaction is the maximum number of requests received in a transaction.
+	MaxRequestsPerTransaction int
+}
+
+// DefaultLimits are the limits used when no limits are set.
+var DefaultLimits = Limits{
+	MaxMessageSize:           64 << 20,
+	MaxTransactionNameLength: 1024,
+}
+
+// SetLimits sets the limits on the data received in this transaction.
+func (s *Stream) SetLimits(limits Limits) {
+	s.limits = limits
+}
+
+// Limits returns the limits on the data received in this transaction.
+func (s *Stream) Limits() Limits {
+	return s.limits
+}
+
+// SetMaxSendMessageSize sets the maximum size of a message sent in this transaction.
+// It is the maximum message size received by the peer, so the peer does not reset the transaction for a message it cannot receive.
+// A size of 0 or less means unlimited.
+// This method is used internally by quics-protocol.
+// So, you may don't need to use it directly.
+func (s *Stream) SetMaxSendMessageSize(size int64) {
+	s.sendLimit = size
+}
+
+// checkLimit returns a *qpErr.LimitError and resets the stream with qpErr.LimitExceededCode if the value exceeds the limit.
+func (s *Stream) checkLimit(limit string, value int64, max int64) error {
+	if max <= 0 || value <= max {
+		return nil
+	}
+	return s.exceedLimit(limit, value, max)
+}
+
+// exceedLimit returns a *qpErr.LimitError and resets the stream with qpErr.LimitExceededCode.
+func (s *Stream) exceedLimit(limit string, value int64, max int64) error {
+	err := &qpErr.LimitError{
+		Limit: limit,
+		Value: value,
+		Max:   max,
+	}
+	log.Println("quics-protocol: ", err)
+	s.reportError(qpErr.LimitExceededCode)
+	s.Stream.CancelRead(qpErr.LimitExceededCode)
+	s.Stream.CancelWrite(qpErr.LimitExceededCode)
+	return err
+}
+
+// checkSendLimit returns a *qpErr.LimitError if the size of a message to send exceeds the maximum message size received by the peer.
+func (s *Stream) checkSendLimit(size int) error {
+	if s.sendLimit <= 0 || int64(size) <= s.sendLimit {
+		return nil
+	}
+	return &qpErr.LimitError{
+		Limit: "message size",
+		Value: int64(size),
+		Max:   s.sendLimit,
+	}
+}
@@ -0,0 +1,141 @@
+package stream
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-s/quics-protocol/pkg/metrics"
+	pb "github.com/quic-s/quics-protocol/proto/v1"
+)
+
+// Counters count the data sent and received in the transactions sharing them.
+// A connection shares its counters with the streams of all its transactions.
+type Counters struct {
+	BytesSent        atomic.Int64
+	BytesReceived    atomic.Int64
+	RequestsSent     atomic.Int64
+	RequestsReceived atomic.Int64
+}
+
+// SetCounters sets the counters of the data sent and received in this transaction.
+// This method is used internally by quics-protocol.
+// So, you may don't need to use it directly.
+func (s *Stream) SetCounters(counters *Counters) {
+	s.counting.counters.Store(counters)
+}
+
+// SetMetrics sets the metrics that the bytes sent and received in this transaction are reported to by request type.
+// This method is used internally by quics-protocol.
+// So, you may don't need to use it directly.
+func (s *Stream) SetMetrics(m metrics.Metrics) {
+	s.counting.mutex.Lock()
+	defer s.counting.mutex.Unlock()
+	s.counting.metrics = m
+}
+
+// countingStream counts the bytes read from and written to the stream.
+// The bytes are reported to the metrics by the request type of the header sent or received last.
+type countingStream struct {
+	quic.Stream
+
+	counters atomic.Pointer[Counters]
+
+	mutex    sync.Mutex
+	metrics  metrics.Metrics
+	sendType string
+	recvType string
+	pending  int64
+}
+
+func (c *countingStream) Read(p []byte) (int, error) {
+	n, err := c.Stream.Read(p)
+	if counters := c.counters.Load(); counters != nil {
+		counters.BytesReceived.Add(int64(n))
+	}
+	c.mutex.Lock()
+	c.pending += int64(n)
+	c.mutex.Unlock()
+	return n, err
+}
+
+func (c *countingStream) Write(p []byte) (int, error) {
+	n, err := c.Stream.Write(p)
+	if counters := c.counters.Load(); counters != nil {
+		counters.BytesSent.Add(int64(n))
+	}
+	c.mutex.Lock()
+	m, requestType := c.metrics, c.sendType
+	c.mutex.Unlock()
+	if m != nil && n > 0 {
+		m.BytesSent(requestType, int64(n))
+	}
+	return n, err
+}
+
+// sending sets the request type of the bytes written after it.
+func (c *countingStream) sending(requestType string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.sendType = requestType
+}
+
+// received reports the bytes read before the header of the request type to the previous request type,
+// and the header bytes to the request type. The bytes read after it are of the request type.
+func (c *countingStream) received(requestType string, headerBytes int64) {
+	c.mutex.Lock()
+	m, previousType, pending := c.metrics, c.recvType, c.pending
+	c.recvType = requestType
+	c.pending = 0
+	c.mutex.Unlock()
+	if m == nil {
+		return
+	}
+	if pending > headerBytes {
+		m.BytesReceived(previousType, pending-headerBytes)
+	}
+	m.BytesReceived(requestType, headerBytes)
+}
+
+// flush reports the bytes read since the last header.
+func (c *countingStream) flush() {
+	c.mutex.Lock()
+	m, requestType, pending := c.metrics, c.recvType, c.pending
+	c.pending = 0
+	c.mutex.Unlock()
+	if m != nil && pending > 0 {
+		m.BytesReceived(requestType, pending)
+	}
+}
+
+// requestTypeLabel returns the request type reported to the metrics.
+// The request types unknown to this version are reported as "unknown", so the peer cannot make the metrics grow without bound.
+func requestTypeLabel(requestType pb.RequestType) string {
+	if _, ok := pb.RequestType_name[int32(requestType)]; !ok {
+		return "unknown"
+	}
+	return requestType.String()
+}
+
+// countRequest counts a request sent or received in this transaction.
+func (s *Stream) countRequest(sent bool) {
+	counters := s.counting.counters.Load()
+	if counters == nil {
+		return
+	}
+	if sent {
+		counters.RequestsSent.Add(1)
+	} else {
+		counters.RequestsReceived.Add(1)
+	}
+}
+
+// reportError reports the error code that this transaction is reset with to the metrics.
+func (s *Stream) reportError(code uint64) {
+	s.counting.mutex.Lock()
+	m := s.counting.metrics
+	s.counting.mutex.Unlock()
+	if m != nil {
+		m.Error(code)
+	}
+}
@@ -2,17 +2,22 @@ package stream
 
 import (
 	"bufio"
+	"context"
 	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
 	"log"
+	"math"
 	"os"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/quic-go/quic-go"
 	qpErr "github.com/quic-s/quics-protocol/pkg/error"
 	qpLog "github.com/quic-s/quics-protocol/pkg/log"
+	"github.com/quic-s/quics-protocol/pkg/progress"
+	"github.com/quic-s/quics-protocol/pkg/ratelimit"
 	"github.com/quic-s/quics-protocol/pkg/types/fileinfo"
 	pb "github.com/quic-s/quics-protocol/proto/v1"
 	"google.golang.org/protobuf/proto"
@@ -21,8 +26,19 @@ import (
 // Stream is a stream instance that is created when a transaction is opened.
 // You can send and receive messages and files multiple times within a single transaction.
 type Stream struct {
-	logLevel int
-	Stream   quic.Stream
+	logLevel        int
+	Stream          quic.Stream
+	ctx             context.Context
+	uploadLimiter   *ratelimit.Limiter
+	downloadLimiter *ratelimit.Limiter
+	tracker         *progress.Tracker
+	metadata        fileinfo.Metadata
+	sparseSupported bool
+	limits          Limits
+	sendLimit       int64
+	requests        int
+	timeout         *timeoutStream
+	counting        *countingStream
 }
 
 // New creates a new stream instance.
@@ -32,12 +48,88 @@ func New(logLevel int, stream quic.Stream) (*Stream, error) {
 	if stream == nil {
 		return nil, errors.New("stream is nil")
 	}
+	counting := &countingStream{Stream: stream}
+	timeout := &timeoutStream{Stream: counting}
 	return &Stream{
-		logLevel: logLevel,
-		Stream:   stream,
+		logLevel:        logLevel,
+		Stream:          timeout,
+		ctx:             context.Background(),
+		uploadLimiter:   ratelimit.New(0, nil),
+		downloadLimiter: ratelimit.New(0, nil),
+		limits:          DefaultLimits,
+		timeout:         timeout,
+		counting:        counting,
 	}, nil
 }
 
+// SetMetadata sets the optional file metadata to send with files in this transaction.
+// By default, no optional metadata is sent and symbolic links are followed.
+func (s *Stream) SetMetadata(metadata fileinfo.Metadata) {
+	s.metadata = metadata
+}
+
+// SetSparseSupported sets whether the peer supports receiving sparse files.
+// Sparse files are sent as data extents only if the peer supports them, because older peers read the whole size of the content.
+// This method is used internally by quics-protocol.
+// So, you may don't need to use it directly.
+func (s *Stream) SetSparseSupported(supported bool) {
+	s.sparseSupported = supported
+}
+
+// SetParentLimiters sets the upload and download limiters of the connection as parents of the limiters of the stream.
+// This method is used internally by quics-protocol.
+// So, you may don't need to use it directly.
+func (s *Stream) SetParentLimiters(uploadLimiter *ratelimit.Limiter, downloadLimiter *ratelimit.Limiter) {
+	s.uploadLimiter.SetParent(uploadLimiter)
+	s.downloadLimiter.SetParent(downloadLimiter)
+}
+
+// SetUploadLimit limits the rate of sending data of this transaction in bytes per second.
+// The limits of the connection and the quics-protocol instance are applied together.
+// The limit can be changed at any time. A limit of 0 or less means unlimited.
+func (s *Stream) SetUploadLimit(bytesPerSecond int64) {
+	s.uploadLimiter.SetLimit(bytesPerSecond)
+}
+
+// SetDownloadLimit limits the rate of receiving data of this transaction in bytes per second.
+// The limits of the connection and the quics-protocol instance are applied together.
+// The limit can be changed at any time. A limit of 0 or less means unlimited.
+func (s *Stream) SetDownloadLimit(bytesPerSecond int64) {
+	s.downloadLimiter.SetLimit(bytesPerSecond)
+}
+
+// SetProgressFunc sets the callback function reporting the progress of transfers through this stream.
+// It works on both the sending side and the receiving side of files, bytes messages and directories.
+// The callback is called at most once per interval while data is transferred, and always when each entry is finished.
+// The callback is called in the goroutine sending or receiving the data, so it must not block for long.
+func (s *Stream) SetProgressFunc(interval time.Duration, callback func(progress progress.Progress)) {
+	s.tracker = progress.New(interval, callback)
+}
+
+// Writer returns a writer of the stream limited by the upload limits.
+// Waiting for the limits stops when the stream is closed or reset.
+// This method is used internally to send headers, file data and messages.
+// So, you may don't need to use it directly.
+func (s *Stream) Writer() io.Writer {
+	return ratelimit.NewWriter(s.Stream.Context(), s.Stream, s.uploadLimiter)
+}
+
+// Reader returns a reader of the stream limited by the download limits.
+// Waiting for the limits stops when the connection is closed. (see SetContext)
+// Unlike writing, it does not stop when this side of the stream is closed, so the response of the peer can still be read.
+// This method is used internally to receive headers, file data and messages.
+// So, you may don't need to use it directly.
+func (s *Stream) Reader() io.Reader {
+	return ratelimit.NewReader(s.ctx, s.Stream, s.downloadLimiter)
+}
+
+// SetContext sets the context of the connection of the stream. Waiting for the download limits stops when it is done.
+// This method is used internally by quics-protocol.
+// So, you may don't need to use it directly.
+func (s *Stream) SetContext(ctx context.Context) {
+	s.ctx = ctx
+}
+
 // Close closes the stream.
 // Stream is closed automatically when the transaction is closed.
 // So, you may don't need to use it directly.
@@ -46,6 +138,7 @@ func (s *Stream) Close() error {
 		return errors.New("stream is nil")
 	}
 
+	s.counting.flush()
 	err := s.Stream.Close()
 	if err != nil {
 		return err
@@ -83,6 +176,10 @@ func (s *Stream) SendBMessage(data []byte) error {
 	if s == nil || s.Stream == nil {
 		return errors.New("stream is nil")
 	}
+	err := s.checkSendLimit(len(data))
+	if err != nil {
+		return err
+	}
 	requestId, err := uuid.New().MarshalBinary()
 	if err != nil {
 		return err
@@ -92,10 +189,12 @@ func (s *Stream) SendBMessage(data []byte) error {
 		return err
 	}
 
+	s.tracker.Begin("", int64(len(data)))
 	err = WriteMessage(s, data)
 	if err != nil {
 		return err
 	}
+	s.tracker.Add(int64(len(data)))
 	return nil
 }
 
@@ -137,6 +236,10 @@ func (s *Stream) SendFileBMessage(data []byte, filePath string) error {
 	if s == nil || s.Stream == nil {
 		return errors.New("stream is nil")
 	}
+	err := s.checkSendLimit(len(data))
+	if err != nil {
+		return err
+	}
 	requestId, err := uuid.New().MarshalBinary()
 	if err != nil {
 		return err
@@ -146,10 +249,12 @@ func (s *Stream) SendFileBMessage(data []byte, filePath string) error {
 		return err
 	}
 
+	s.tracker.Begin("", int64(len(data)))
 	err = WriteMessage(s, data)
 	if err != nil {
 		return err
 	}
+	s.tracker.Add(int64(len(data)))
 
 	err = WriteFile(s, filePath)
 	if err != nil {
@@ -174,6 +279,8 @@ func (s *Stream) RecvBMessage() ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
+	s.tracker.Begin("", int64(len(message)))
+	s.tracker.Add(int64(len(message)))
 
 	return message, nil
 }
@@ -214,6 +321,8 @@ func (s *Stream) RecvFileBMessage() ([]byte, *fileinfo.FileInfo, io.Reader, erro
 	if err != nil {
 		return nil, nil, nil, err
 	}
+	s.tracker.Begin("", int64(len(message)))
+	s.tracker.Add(int64(len(message)))
 
 	fileInfo, fileReader, err := ReadFile(s)
 	if err != nil {
@@ -242,10 +351,12 @@ func WriteHeader(s *Stream, requestType pb.RequestType, requestId []byte, errorM
 		log.Println("quics-protocol: ", "sending ", cap(buf), "bytes")
 	}
 
-	n, err := s.Stream.Write(buf)
+	s.counting.sending(requestTypeLabel(requestType))
+	n, err := s.Writer().Write(buf)
 	if err != nil {
 		return err
 	}
+	s.countRequest(true)
 	if s.logLevel <= qpLog.INFO {
 		log.Println("quics-protocol: ", "sent", n)
 	}
@@ -253,6 +364,13 @@ func WriteHeader(s *Stream, requestType pb.RequestType, requestId []byte, errorM
 }
 
 func WriteMessage(s *Stream, data []byte) error {
+	err := s.checkSendLimit(len(data))
+	if err != nil {
+		// The header of the request is already sent, so the request cannot be completed.
+		s.reportError(qpErr.LimitExceededCode)
+		s.Stream.CancelWrite(qpErr.LimitExceededCode)
+		return err
+	}
 	buf := make([]byte, 4, 4+len(data))
 	binary.BigEndian.PutUint32(buf[:4], uint32(len(data)))
 
@@ -262,7 +380,7 @@ func WriteMessage(s *Stream, data []byte) error {
 		log.Println("quics-protocol: ", "sending ", cap(buf), "bytes")
 	}
 
-	n, err := s.Stream.Write(buf)
+	n, err := s.Writer().Write(buf)
 	if err != nil {
 		return err
 	}
@@ -273,25 +391,96 @@ func WriteMessage(s *Stream, data []byte) error {
 }
 
 func WriteFile(s *Stream, filePath string) error {
+	return writeFile(s, filePath, "", "")
+}
+
+// writeFile sends the file with the name. If the name is empty, the base name of the file is used.
+// writeFile writes the file with the name instead of its base name if the name is not empty.
+// If the hard link target is not empty, the file is sent as a hard link to it without content.
+func writeFile(s *Stream, filePath string, name string, hardlinkTarget string) error {
+	qpFileInfo, err := fileinfo.NewFromPath(filePath, s.metadata)
+	if err != nil {
+		log.Println("quics-protocol: ", err)
+		return err
+	}
+	if name != "" {
+		qpFileInfo.Name = name
+	}
+	qpFileInfo.HardlinkTarget = hardlinkTarget
+	if !qpFileInfo.HasContent() {
+		err = WriteFileInfo(s, qpFileInfo)
+		if err != nil {
+			return err
+		}
+		s.tracker.Begin(qpFileInfo.Name, 0)
+		return nil
+	}
+
 	file, err := os.Open(filePath)
 	if err != nil {
 		log.Println("quics-protocol: ", err)
 		return err
 	}
 	defer file.Close()
+	if s.metadata&fileinfo.MetadataSparse != 0 && s.sparseSupported {
+		extents, err := fileinfo.DataExtents(file, qpFileInfo.Size)
+		if err != nil {
+			log.Println("quics-protocol: ", err)
+			return err
+		}
+		if extents != nil {
+			qpFileInfo.Sparse = true
+			qpFileInfo.Extents = extents
+		}
+	}
+
+	err = WriteFileInfo(s, qpFileInfo)
+	if err != nil {
+		return err
+	}
 
-	osFileInfo, err := file.Stat()
+	dataSize := qpFileInfo.DataSize()
+	s.tracker.Begin(qpFileInfo.Name, dataSize)
+	if s.logLevel <= qpLog.INFO {
+		log.Println("quics-protocol: ", "sending fileInfo ", dataSize, "bytes")
+	}
+	var content io.Reader = file
+	if qpFileInfo.Sparse {
+		// Only the data extents are sent, so the holes are skipped.
+		readers := make([]io.Reader, 0, len(qpFileInfo.Extents))
+		for _, extent := range qpFileInfo.Extents {
+			readers = append(readers, io.NewSectionReader(file, extent.Offset, extent.Length))
+		}
+		content = io.MultiReader(readers...)
+	}
+	num, err := io.CopyN(s.tracker.Writer(s.Writer()), content, dataSize)
 	if err != nil {
 		log.Println("quics-protocol: ", err)
 		return err
 	}
+	if num != dataSize {
+		return errors.New("write size is not equal to file size")
+	}
+	if s.logLevel <= qpLog.INFO {
+		log.Println("quics-protocol: ", "sent", num, "bytes")
+	}
 
-	qpFileInfo, err := fileinfo.NewFromOSFileInfo(osFileInfo)
+	afterFileInfo, err := file.Stat()
 	if err != nil {
 		log.Println("quics-protocol: ", err)
 		return err
 	}
 
+	if qpFileInfo.ModTime != afterFileInfo.ModTime() || qpFileInfo.Size != afterFileInfo.Size() || qpFileInfo.Mode != afterFileInfo.Mode() {
+		s.reportError(qpErr.FileModifiedDuringTransferCode)
+		s.Stream.CancelWrite(qpErr.FileModifiedDuringTransferCode)
+		log.Println("quics-protocol: file is modified during transfer")
+		return qpErr.ErrFileModifiedDuringTransfer
+	}
+	return nil
+}
+
+func WriteFileInfo(s *Stream, qpFileInfo *fileinfo.FileInfo) error {
 	pbFileInfo, err := qpFileInfo.ToProtobuf()
 	if err != nil {
 		log.Println("quics-protocol: ", err)
@@ -304,6 +493,9 @@ func WriteFile(s *Stream, filePath string) error {
 		return err
 	}
 
+	if len(fileInfoOut) > math.MaxUint16 {
+		return errors.New("file info is too large")
+	}
 	buf := make([]byte, 2, 2+len(fileInfoOut))
 	binary.BigEndian.PutUint16(buf[:2], uint16(len(fileInfoOut)))
 	buf = append(buf, fileInfoOut...)
@@ -311,7 +503,7 @@ func WriteFile(s *Stream, filePath string) error {
 	if s.logLevel <= qpLog.INFO {
 		log.Println("quics-protocol: ", "sending fileInfo ", cap(buf), "bytes")
 	}
-	n, err := s.Stream.Write(buf)
+	n, err := s.Writer().Write(buf)
 	if err != nil {
 		log.Println("quics-protocol: ", err)
 		return err
@@ -322,35 +514,6 @@ func WriteFile(s *Stream, filePath string) error {
 	if s.logLevel <= qpLog.INFO {
 		log.Println("quics-protocol: ", "sent", n, "bytes")
 	}
-
-	if !qpFileInfo.IsDir {
-		if s.logLevel <= qpLog.INFO {
-			log.Println("quics-protocol: ", "sending fileInfo ", qpFileInfo.Size, "bytes")
-		}
-		num, err := io.CopyN(s.Stream, file, qpFileInfo.Size)
-		if err != nil {
-			log.Println("quics-protocol: ", err)
-			return err
-		}
-		if num != qpFileInfo.Size {
-			return errors.New("write size is not equal to file size")
-		}
-		if s.logLevel <= qpLog.INFO {
-			log.Println("quics-protocol: ", "sent", num, "bytes")
-		}
-	}
-
-	afterFileInfo, err := file.Stat()
-	if err != nil {
-		log.Println("quics-protocol: ", err)
-		return err
-	}
-
-	if qpFileInfo.ModTime != afterFileInfo.ModTime() || qpFileInfo.Size != afterFileInfo.Size() || qpFileInfo.Mode != afterFileInfo.Mode() {
-		s.Stream.CancelWrite(qpErr.FileModifiedDuringTransferCode)
-		log.Println("quics-protocol: file is modified during transfer")
-		return qpErr.ErrFileModifiedDuringTransfer
-	}
 	return nil
 }
 
@@ -374,7 +537,7 @@ func WriteTransaction(s *Stream, transactionName string, transactionID []byte) e
 		log.Println("quics-protocol: ", "sending ", cap(buf), "bytes")
 	}
 
-	n, err := s.Stream.Write(buf)
+	n, err := s.Writer().Write(buf)
 	if err != nil {
 		log.Println("quics-protocol: ", err)
 		return err
@@ -390,7 +553,7 @@ func ReadHeader(s *Stream) (*pb.Header, error) {
 	if s.logLevel <= qpLog.INFO {
 		log.Println("quics-protocol: ", "read header size")
 	}
-	n, err := io.ReadFull(s.Stream, headerSizeBuf)
+	n, err := io.ReadFull(s.Reader(), headerSizeBuf)
 	if err != nil {
 		log.Println("quics-protocol: ", err)
 		return nil, err
@@ -403,7 +566,7 @@ func ReadHeader(s *Stream) (*pb.Header, error) {
 	if s.logLevel <= qpLog.INFO {
 		log.Println("quics-protocol: ", "read header")
 	}
-	n, err = io.ReadFull(s.Stream, headerBuf)
+	n, err = io.ReadFull(s.Reader(), headerBuf)
 	if err != nil {
 		log.Println("quics-protocol: ", err)
 		return nil, err
@@ -413,9 +576,21 @@ func ReadHeader(s *Stream) (*pb.Header, error) {
 	}
 	header := &pb.Header{}
 	proto.Unmarshal(headerBuf, header)
+	s.countRequest(false)
+	s.counting.received(requestTypeLabel(header.RequestType), int64(2+headerSize))
+	if _, ok := pb.RequestType_name[int32(header.RequestType)]; !ok {
+		return nil, fmt.Errorf("unknown request type %d", header.RequestType)
+	}
 	if s.logLevel <= qpLog.INFO {
 		log.Println("quics-protocol: ", header.RequestType, header.RequestType, header.RequestId)
 	}
+	if header.RequestType != pb.RequestType_TRANSACTION {
+		s.requests++
+		err = s.checkLimit("requests per transaction", int64(s.requests), int64(s.limits.MaxRequestsPerTransaction))
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	if header.Error != "" {
 		return nil, errors.New(header.Error)
@@ -429,7 +604,7 @@ func ReadMessage(s *Stream) ([]byte, error) {
 	if s.logLevel <= qpLog.INFO {
 		log.Println("quics-protocol: ", "read message size")
 	}
-	n, err := io.ReadFull(s.Stream, messageSizeBuf)
+	n, err := io.ReadFull(s.Reader(), messageSizeBuf)
 	if err != nil {
 		log.Println("quics-protocol: ", err)
 		return nil, err
@@ -439,11 +614,15 @@ func ReadMessage(s *Stream) ([]byte, error) {
 	}
 
 	messageSize := uint32(binary.BigEndian.Uint32(messageSizeBuf))
+	err = s.checkLimit("message size", int64(messageSize), s.limits.MaxMessageSize)
+	if err != nil {
+		return nil, err
+	}
 	if s.logLevel <= qpLog.INFO {
 		log.Println("quics-protocol: ", "read message")
 	}
 	messageBuf := make([]byte, messageSize)
-	n, err = io.ReadFull(s.Stream, messageBuf)
+	n, err = io.ReadFull(s.Reader(), messageBuf)
 	if err != nil {
 		log.Println("quics-protocol: ", err)
 		return nil, err
@@ -455,28 +634,54 @@ func ReadMessage(s *Stream) ([]byte, error) {
 }
 
 func ReadFile(s *Stream) (*fileinfo.FileInfo, io.Reader, error) {
+	fileInfo, err := ReadFileInfo(s)
+	if err != nil {
+		return nil, nil, err
+	}
+	if s.logLevel <= qpLog.INFO {
+		log.Println("quics-protocol: ", "read file")
+	}
+
+	size := fileInfo.DataSize()
+	if !fileInfo.HasContent() {
+		size = 0
+	}
+	s.tracker.Begin(fileInfo.Name, size)
+	fileReader := io.LimitReader(s.tracker.Reader(s.Reader()), size)
+	if s.logLevel <= qpLog.INFO {
+		log.Println("quics-protocol: ", "init file reader with size", size)
+	}
+	fileBufReader := bufio.NewReader(fileReader)
+	if fileInfo.Sparse {
+		// The holes are filled with zeros when the file content is read.
+		return fileInfo, fileinfo.NewSparseReader(fileBufReader, fileInfo.Extents, fileInfo.Size), nil
+	}
+	return fileInfo, fileBufReader, nil
+}
+
+func ReadFileInfo(s *Stream) (*fileinfo.FileInfo, error) {
 	fileInfoSizeBuf := make([]byte, 2)
 	if s.logLevel <= qpLog.INFO {
 		log.Println("quics-protocol: ", "read file info size")
 	}
-	n, err := io.ReadFull(s.Stream, fileInfoSizeBuf)
+	n, err := io.ReadFull(s.Reader(), fileInfoSizeBuf)
 	if err != nil {
 		log.Println("quics-protocol: ", err)
-		return nil, nil, err
+		return nil, err
 	}
 	if n != 2 {
-		return nil, nil, errors.New("file info size is not 2 bytes")
+		return nil, errors.New("file info size is not 2 bytes")
 	}
 	fileInfoSize := uint16(binary.BigEndian.Uint16(fileInfoSizeBuf))
 
 	fileInfoBuf := make([]byte, fileInfoSize)
-	n, err = io.ReadFull(s.Stream, fileInfoBuf)
+	n, err = io.ReadFull(s.Reader(), fileInfoBuf)
 	if err != nil {
 		log.Println("quics-protocol: ", err)
-		return nil, nil, err
+		return nil, err
 	}
 	if n != int(fileInfoSize) {
-		return nil, nil, fmt.Errorf("file info size is not %d bytes", fileInfoSize)
+		return nil, fmt.Errorf("file info size is not %d bytes", fileInfoSize)
 	}
 
 	protoFileInfo := &pb.FileInfo{}
@@ -485,19 +690,16 @@ func ReadFile(s *Stream) (*fileinfo.FileInfo, io.Reader, error) {
 	fileInfo, err := fileinfo.NewFromProtobuf(protoFileInfo)
 	if err != nil {
 		log.Println("quics-protocol: ", err)
-		return nil, nil, err
+		return nil, err
 	}
-	if s.logLevel <= qpLog.INFO {
-		log.Println("quics-protocol: ", fileInfo.Name, fileInfo.Size, "bytes")
-		log.Println("quics-protocol: ", "read file")
+	err = s.checkLimit("file size", fileInfo.Size, s.limits.MaxFileSize)
+	if err != nil {
+		return nil, err
 	}
-
-	fileReader := io.LimitReader(s.Stream, fileInfo.Size)
 	if s.logLevel <= qpLog.INFO {
-		log.Println("quics-protocol: ", "init file reader with size", fileInfo.Size)
+		log.Println("quics-protocol: ", fileInfo.Name, fileInfo.Size, "bytes")
 	}
-	fileBufReader := bufio.NewReader(fileReader)
-	return fileInfo, fileBufReader, nil
+	return fileInfo, nil
 }
 
 func ReadTransaction(s *Stream) (*pb.Transaction, error) {
@@ -505,7 +707,7 @@ func ReadTransaction(s *Stream) (*pb.Transaction, error) {
 	if s.logLevel <= qpLog.INFO {
 		log.Println("quics-protocol: ", "read transaction size")
 	}
-	n, err := io.ReadFull(s.Stream, transactionSizeBuf)
+	n, err := io.ReadFull(s.Reader(), transactionSizeBuf)
 	if err != nil {
 		log.Println("quics-protocol: ", err)
 		return nil, err
@@ -516,7 +718,7 @@ func ReadTransaction(s *Stream) (*pb.Transaction, error) {
 	transactionSize := uint16(binary.BigEndian.Uint16(transactionSizeBuf))
 
 	transactionBuf := make([]byte, transactionSize)
-	n, err = io.ReadFull(s.Stream, transactionBuf)
+	n, err = io.ReadFull(s.Reader(), transactionBuf)
 	if err != nil {
 		log.Println("quics-protocol: ", err)
 		return nil, err
@@ -527,6 +729,10 @@ func ReadTransaction(s *Stream) (*pb.Transaction, error) {
 
 	transaction := &pb.Transaction{}
 	proto.Unmarshal(transactionBuf, transaction)
+	err = s.checkLimit("transaction name length", int64(len(transaction.TransactionName)), int64(s.limits.MaxTransactionNameLength))
+	if err != nil {
+		return nil, err
+	}
 	if s.logLevel <= qpLog.INFO {
 		log.Println("quics-protocol: ", transaction.TransactionName, transaction.TransactionID)
 	}
@@ -0,0 +1,127 @@
+package stream
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	qpErr "github.com/quic-s/quics-protocol/pkg/error"
+)
+
+// Timeouts are the timeouts against peers that open transactions and stay silent.
+// A timeout of 0 or less means no timeout.
+type Timeouts struct {
+	// HandshakeTimeout is the maximum time to receive the transaction handshake after a transaction is accepted.
+	HandshakeTimeout time.Duration
+	// IdleTimeout is the maximum time each read and write of a transaction waits for the peer.
+	IdleTimeout time.Duration
+	// MaxTimeoutsPerConnection closes the connection when this many transactions of it timed out.
+	MaxTimeoutsPerConnection int
+}
+
+// DefaultTimeouts are the timeouts used when no timeouts are set.
+var DefaultTimeouts = Timeouts{
+	HandshakeTimeout: 10 * time.Second,
+}
+
+// SetIdleTimeout sets the maximum time each read and write of this transaction waits for the peer.
+// When it is exceeded, the stream is reset with qpErr.IdleTimeoutCode and qpErr.ErrIdleTimeout is returned.
+// A timeout of 0 or less means no timeout.
+func (s *Stream) SetIdleTimeout(timeout time.Duration) {
+	s.timeout.mutex.Lock()
+	defer s.timeout.mutex.Unlock()
+	s.timeout.idle = timeout
+}
+
+// SetTimeoutFunc sets the function called when this transaction is reset by the idle timeout.
+// This method is used internally by quics-protocol.
+// So, you may don't need to use it directly.
+func (s *Stream) SetTimeoutFunc(timeoutFunc func()) {
+	s.timeout.mutex.Lock()
+	defer s.timeout.mutex.Unlock()
+	s.timeout.timeoutFunc = timeoutFunc
+}
+
+// timeoutStream refreshes the deadline of the stream before each read and write,
+// so a read or write waiting for a silent peer fails after the idle timeout.
+// Deadlines set through it are kept, and the earlier of the deadline and the idle timeout is used.
+type timeoutStream struct {
+	quic.Stream
+
+	mutex         sync.Mutex
+	idle          time.Duration
+	readDeadline  time.Time
+	writeDeadline time.Time
+	timeoutFunc   func()
+}
+
+func (t *timeoutStream) Read(p []byte) (int, error) {
+	idle := t.refresh(&t.readDeadline, t.Stream.SetReadDeadline)
+	n, err := t.Stream.Read(p)
+	return n, t.check(err, idle)
+}
+
+func (t *timeoutStream) Write(p []byte) (int, error) {
+	idle := t.refresh(&t.writeDeadline, t.Stream.SetWriteDeadline)
+	n, err := t.Stream.Write(p)
+	return n, t.check(err, idle)
+}
+
+func (t *timeoutStream) SetReadDeadline(deadline time.Time) error {
+	t.mutex.Lock()
+	t.readDeadline = deadline
+	t.mutex.Unlock()
+	return t.Stream.SetReadDeadline(deadline)
+}
+
+func (t *timeoutStream) SetWriteDeadline(deadline time.Time) error {
+	t.mutex.Lock()
+	t.writeDeadline = deadline
+	t.mutex.Unlock()
+	return t.Stream.SetWriteDeadline(deadline)
+}
+
+func (t *timeoutStream) SetDeadline(deadline time.Time) error {
+	t.mutex.Lock()
+	t.readDeadline = deadline
+	t.writeDeadline = deadline
+	t.mutex.Unlock()
+	return t.Stream.SetDeadline(deadline)
+}
+
+// refresh sets the deadline of the next read or write, and reports whether the idle timeout is used for it.
+func (t *timeoutStream) refresh(deadline *time.Time, setDeadline func(time.Time) error) bool {
+	t.mutex.Lock()
+	idle := t.idle
+	fixed := *deadline
+	t.mutex.Unlock()
+	if idle <= 0 {
+		return false
+	}
+	idleDeadline := time.Now().Add(idle)
+	if !fixed.IsZero() && fixed.Before(idleDeadline) {
+		setDeadline(fixed)
+		return false
+	}
+	setDeadline(idleDeadline)
+	return true
+}
+
+// check resets the stream if the error is caused by the idle timeout.
+func (t *timeoutStream) check(err error, idle bool) error {
+	netErr := net.Error(nil)
+	if !idle || !errors.As(err, &netErr) || !netErr.Timeout() {
+		return err
+	}
+	t.Stream.CancelRead(qpErr.IdleTimeoutCode)
+	t.Stream.CancelWrite(qpErr.IdleTimeoutCode)
+	t.mutex.Lock()
+	timeoutFunc := t.timeoutFunc
+	t.mutex.Unlock()
+	if timeoutFunc != nil {
+		timeoutFunc()
+	}
+	return qpErr.ErrIdleTimeout
+}
@@ -0,0 +1,262 @@
+package tls
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+)
+
+// NextProto is the application protocol negotiated by ALPN in the TLS configurations built by CA.
+const NextProto = "quics-protocol"
+
+// CA is a small certificate authority for private deployments.
+// It can be a root or an intermediate signed by another CA, and it issues server and client certificates.
+type CA struct {
+	// Certificate is the certificate of this CA.
+	Certificate *x509.Certificate
+	// Key is the private key of this CA.
+	Key crypto.Signer
+	// Parents are the certificates of the CAs that signed this CA, ending with the root. It is empty for a root.
+	Parents []*x509.Certificate
+}
+
+// NewRootCA creates a new self-signed root CA with the options. The hosts of the options are ignored.
+func NewRootCA(opts CertificateOptions) (*CA, error) {
+	key, err := GenerateKey(opts.KeyType)
+	if err != nil {
+		return nil, err
+	}
+	template, err := newCATemplate(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := x509.ParseCertificate(certDER)
+	if err != nil {
+		return nil, err
+	}
+	return &CA{
+		Certificate: cert,
+		Key:         key,
+	}, nil
+}
+
+// NewIntermediate creates a new intermediate CA signed by this CA with the options.
+// The intermediate can issue certificates, but it cannot sign other CAs. The hosts of the options are ignored.
+func (ca *CA) NewIntermediate(opts CertificateOptions) (*CA, error) {
+	key, err := GenerateKey(opts.KeyType)
+	if err != nil {
+		return nil, err
+	}
+	template, err := newCATemplate(opts)
+	if err != nil {
+		return nil, err
+	}
+	template.MaxPathLen = 0
+	template.MaxPathLenZero = true
+
+	certDER, err := x509.CreateCertificate(rand.Reader, template, ca.Certificate, key.Public(), ca.Key)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := x509.ParseCertificate(certDER)
+	if err != nil {
+		return nil, err
+	}
+	return &CA{
+		Certificate: cert,
+		Key:         key,
+		Parents:     append([]*x509.Certificate{ca.Certificate}, ca.Parents...),
+	}, nil
+}
+
+// newCATemplate creates a certificate template of a CA.
+func newCATemplate(opts CertificateOptions) (*x509.Certificate, error) {
+	opts.Hosts = nil
+	template, err := newTemplate(opts)
+	if err != nil {
+		return nil, err
+	}
+	template.IsCA = true
+	template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+	return template, nil
+}
+
+// IssueServer issues a new server certificate with the options. The hosts of the options are the SANs of the server.
+func (ca *CA) IssueServer(opts CertificateOptions) (*tls.Certificate, error) {
+	return ca.Issue(opts, []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth})
+}
+
+// IssueClient issues a new client certificate with the options.
+func (ca *CA) IssueClient(opts CertificateOptions) (*tls.Certificate, error) {
+	return ca.Issue(opts, []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth})
+}
+
+// Issue issues a new certificate with the options and the extended key usages.
+// The chain of the certificate includes the intermediate CAs, but not the root.
+func (ca *CA) Issue(opts CertificateOptions, extKeyUsages []x509.ExtKeyUsage) (*tls.Certificate, error) {
+	key, err := GenerateKey(opts.KeyType)
+	if err != nil {
+		return nil, err
+	}
+	certDER, err := ca.Sign(opts, key.Public(), extKeyUsages)
+	if err != nil {
+		return nil, err
+	}
+	return newCertificate(append([][]byte{certDER}, ca.Chain()...), key)
+}
+
+// Sign signs a new certificate of the public key with the options and the extended key usages, and returns it DER encoded.
+// It is used to issue a certificate of a key kept by its owner, for example from a certificate signing request.
+func (ca *CA) Sign(opts CertificateOptions, publicKey crypto.PublicKey, extKeyUsages []x509.ExtKeyUsage) ([]byte, error) {
+	template, err := newTemplate(opts)
+	if err != nil {
+		return nil, err
+	}
+	template.KeyUsage = x509.KeyUsageDigitalSignature
+	template.ExtKeyUsage = extKeyUsages
+	if template.NotAfter.After(ca.Certificate.NotAfter) {
+		template.NotAfter = ca.Certificate.NotAfter
+	}
+	return x509.CreateCertificate(rand.Reader, template, ca.Certificate, publicKey, ca.Key)
+}
+
+// Chain returns the DER encoded certificates of this CA and its parents, except the root.
+// They follow the certificates issued by this CA in their chains.
+func (ca *CA) Chain() [][]byte {
+	if len(ca.Parents) == 0 {
+		return nil
+	}
+	chain := [][]byte{ca.Certificate.Raw}
+	for _, parent := range ca.Parents[:len(ca.Parents)-1] {
+		chain = append(chain, parent.Raw)
+	}
+	return chain
+}
+
+// Root returns the certificate of the root CA.
+func (ca *CA) Root() *x509.Certificate {
+	if len(ca.Parents) == 0 {
+		return ca.Certificate
+	}
+	return ca.Parents[len(ca.Parents)-1]
+}
+
+// CertPool returns a new certificate pool trusting the root CA.
+func (ca *CA) CertPool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.Root())
+	return pool
+}
+
+// BundlePEM returns the PEM encoded certificates of this CA and its parents, ending with the root.
+func (ca *CA) BundlePEM() []byte {
+	bundle := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Certificate.Raw})
+	for _, parent := range ca.Parents {
+		bundle = append(bundle, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: parent.Raw})...)
+	}
+	return bundle
+}
+
+// RootPEM returns the PEM encoded certificate of the root CA, to be distributed to the peers that trust this CA.
+func (ca *CA) RootPEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Root().Raw})
+}
+
+// Write writes the PEM encoded key and certificate bundle of this CA to the files with 0600 permissions. (see WriteCertificate)
+func (ca *CA) Write(keyPath string, certPath string) error {
+	keyDER, err := x509.MarshalPKCS8PrivateKey(ca.Key)
+	if err != nil {
+		return err
+	}
+	err = writeFileAtomic(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}))
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(certPath, ca.BundlePEM())
+}
+
+// LoadCA loads the CA of the key and certificate bundle files written by CA.Write.
+func LoadCA(keyPath string, certPath string) (*CA, error) {
+	keyPEM, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, err
+	}
+	keyBlock, _ := pem.Decode(keyPEM)
+	if keyBlock == nil {
+		return nil, errors.New("no private key is found")
+	}
+	parsedKey, err := x509.ParsePKCS8PrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsedKey.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("private key cannot sign")
+	}
+
+	bundlePEM, err := os.ReadFile(certPath)
+	if err != nil {
+		return nil, err
+	}
+	certs := []*x509.Certificate{}
+	for {
+		var block *pem.Block
+		block, bundlePEM = pem.Decode(bundlePEM)
+		if block == nil {
+			break
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, ErrNoCertificate
+	}
+	if !certs[0].IsCA {
+		return nil, errors.New("certificate is not a CA")
+	}
+	return &CA{
+		Certificate: certs[0],
+		Key:         key,
+		Parents:     certs[1:],
+	}, nil
+}
+
+// ServerTLSConfig builds the TLS configuration of a server with the certificate issued by this CA.
+// The server requires the client certificates issued by the same root CA.
+func (ca *CA) ServerTLSConfig(cert *tls.Certificate) *tls.Config {
+	return &tls.Config{
+		Certificates: []tls.Certificate{*cert},
+		ClientCAs:    ca.CertPool(),
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		NextProtos:   []string{NextProto},
+		MinVersion:   tls.VersionTLS13,
+	}
+}
+
+// ClientTLSConfig builds the TLS configuration of a client with the certificate issued by this CA.
+// The client trusts the server certificates issued by the same root CA for the server name.
+// The certificate can be nil if the server does not require client certificates.
+func (ca *CA) ClientTLSConfig(cert *tls.Certificate, serverName string) *tls.Config {
+	conf := &tls.Config{
+		RootCAs:    ca.CertPool(),
+		ServerName: serverName,
+		NextProtos: []string{NextProto},
+		MinVersion: tls.VersionTLS13,
+	}
+	if cert != nil {
+		conf.Certificates = []tls.Certificate{*cert}
+	}
+	return conf
+}
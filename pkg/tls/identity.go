@@ -0,0 +1,66 @@
+package tls
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/url"
+	"os"
+)
+
+// ErrNoCertificate is returned when a PEM file has no certificate.
+var ErrNoCertificate = errors.New("no certificate is found")
+
+// Identity is the identity of a peer parsed from its certificate.
+type Identity struct {
+	// Subject is the subject of the certificate.
+	Subject pkix.Name
+	// DNSNames, EmailAddresses, IPAddresses and URIs are the subject alternative names (SANs) of the certificate.
+	DNSNames       []string
+	EmailAddresses []string
+	IPAddresses    []net.IP
+	URIs           []*url.URL
+	// Fingerprint is the SPKI fingerprint of the certificate. (see Fingerprint)
+	Fingerprint string
+	// Certificate is the certificate the identity is parsed from.
+	Certificate *x509.Certificate
+}
+
+// NewIdentity parses the identity from the certificate.
+func NewIdentity(cert *x509.Certificate) *Identity {
+	return &Identity{
+		Subject:        cert.Subject,
+		DNSNames:       cert.DNSNames,
+		EmailAddresses: cert.EmailAddresses,
+		IPAddresses:    cert.IPAddresses,
+		URIs:           cert.URIs,
+		Fingerprint:    Fingerprint(cert),
+		Certificate:    cert,
+	}
+}
+
+// Fingerprint returns the SHA-256 hash of the subject public key info (SPKI) of the certificate in lowercase hex.
+// Unlike the hash of the whole certificate, it does not change when the certificate is renewed with the same key.
+func Fingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return hex.EncodeToString(sum[:])
+}
+
+// LoadCertPool loads the PEM encoded CA certificates of the files into a new certificate pool,
+// for example to verify the client certificates. (see QP.SetClientCAs)
+func LoadCertPool(certPaths ...string) (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+	for _, certPath := range certPaths {
+		certPEM, err := os.ReadFile(certPath)
+		if err != nil {
+			return nil, err
+		}
+		if !pool.AppendCertsFromPEM(certPEM) {
+			return nil, ErrNoCertificate
+		}
+	}
+	return pool, nil
+}
@@ -0,0 +1,263 @@
+package tls
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// ErrUnknownHost is returned when the server is not in the known hosts and trust on first use is disabled.
+var ErrUnknownHost = errors.New("host is not in the known hosts")
+
+// ErrInvalidKnownHost is returned when a host and port or a fingerprint cannot be written to the known hosts file,
+// because the host and port is empty or has whitespace, or the fingerprint is not 64 hex characters.
+var ErrInvalidKnownHost = errors.New("invalid host or fingerprint for the known hosts")
+
+// HostKeyMismatchError is returned when the certificate of a server does not match the fingerprints pinned for it.
+type HostKeyMismatchError struct {
+	// HostPort is the host and port of the server.
+	HostPort string
+	// Pinned are the fingerprints pinned for the server.
+	Pinned []string
+	// Received is the fingerprint of the certificate received from the server.
+	Received string
+	// Path is the path of the known hosts file.
+	Path string
+}
+
+func (e *HostKeyMismatchError) Error() string {
+	return fmt.Sprintf("certificate of %s has changed: fingerprint %s is received, but %s is pinned in %s. "+
+		"Someone could be intercepting the connection, or the server certificate has been replaced. "+
+		"If the change is expected, remove %s from the known hosts and connect again",
+		e.HostPort, e.Received, strings.Join(e.Pinned, ", "), e.Path, e.HostPort)
+}
+
+// KnownHosts pins the certificates of servers by their SPKI fingerprints, like the known_hosts of SSH. (see Fingerprint)
+// On the first connection to a server, its fingerprint is recorded (trust on first use),
+// and the later connections are accepted only if the certificate has a pinned fingerprint.
+// The known hosts are stored in a file with a line of "host:port fingerprint" for each pin.
+type KnownHosts struct {
+	path string
+
+	mutex           sync.Mutex
+	hosts           map[string][]string
+	trustOnFirstUse bool
+}
+
+// LoadKnownHosts loads the known hosts of the file. The file is created when a server is pinned if it does not exist.
+// Trust on first use is enabled.
+func LoadKnownHosts(path string) (*KnownHosts, error) {
+	k := &KnownHosts{
+		path:            path,
+		hosts:           make(map[string][]string),
+		trustOnFirstUse: true,
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return k, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid known host at %s:%d", path, line)
+		}
+		k.hosts[fields[0]] = append(k.hosts[fields[0]], strings.ToLower(fields[1]))
+	}
+	return k, scanner.Err()
+}
+
+// SetTrustOnFirstUse sets whether the fingerprint of an unknown server is pinned on the first connection.
+// If it is disabled, only the servers pinned by Pin are accepted.
+func (k *KnownHosts) SetTrustOnFirstUse(trustOnFirstUse bool) {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	k.trustOnFirstUse = trustOnFirstUse
+}
+
+// Fingerprints returns the fingerprints pinned for the host and port.
+func (k *KnownHosts) Fingerprints(hostPort string) []string {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	return append([]string{}, k.hosts[hostPort]...)
+}
+
+// Pin pins the fingerprint for the host and port explicitly, in addition to the fingerprints pinned before,
+// and writes the known hosts file. A server can have more than one fingerprint, for example while its certificate is rotated.
+// It returns ErrInvalidKnownHost if the fingerprint is not 64 hex characters or the host and port has whitespace.
+// If the file cannot be written, the fingerprint is not pinned.
+func (k *KnownHosts) Pin(hostPort string, fingerprint string) error {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	fingerprint = strings.ToLower(fingerprint)
+	if !validHostPort(hostPort) || !validFingerprint(fingerprint) {
+		return ErrInvalidKnownHost
+	}
+	pinned := k.hosts[hostPort]
+	for _, pinnedFingerprint := range pinned {
+		if pinnedFingerprint == fingerprint {
+			return nil
+		}
+	}
+	return k.update(hostPort, append(append([]string{}, pinned...), fingerprint))
+}
+
+// Unpin removes all fingerprints of the host and port and writes the known hosts file.
+// If the file cannot be written, the fingerprints are kept.
+func (k *KnownHosts) Unpin(hostPort string) error {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	return k.update(hostPort, nil)
+}
+
+// Verify checks the certificate chain received from the server of the host and port.
+// An unknown server is accepted if trust on first use is enabled, but it is not pinned until the connection is verified. (see VerifyConnection)
+// It returns *HostKeyMismatchError if the certificate does not match the pinned fingerprints.
+func (k *KnownHosts) Verify(hostPort string, rawCerts [][]byte) error {
+	if len(rawCerts) == 0 {
+		return ErrNoCertificate
+	}
+	cert, err := x509.ParseCertificate(rawCerts[0])
+	if err != nil {
+		return err
+	}
+
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	return k.check(hostPort, Fingerprint(cert), false)
+}
+
+// VerifyPeerCertificate returns the function for tls.Config.VerifyPeerCertificate that checks the server of the host and port.
+func (k *KnownHosts) VerifyPeerCertificate(hostPort string) func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+	return func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+		return k.Verify(hostPort, rawCerts)
+	}
+}
+
+// VerifyConnection returns the function for tls.Config.VerifyConnection that checks the server of the host and port again,
+// and pins an unknown server if trust on first use is enabled.
+// If the known hosts file cannot be written, the server is not pinned and the connection fails.
+func (k *KnownHosts) VerifyConnection(hostPort string) func(state tls.ConnectionState) error {
+	return func(state tls.ConnectionState) error {
+		if len(state.PeerCertificates) == 0 {
+			return ErrNoCertificate
+		}
+		fingerprint := Fingerprint(state.PeerCertificates[0])
+
+		k.mutex.Lock()
+		defer k.mutex.Unlock()
+		return k.check(hostPort, fingerprint, true)
+	}
+}
+
+// ClientTLSConfig builds the TLS configuration of a client that connects to the server of the host and port.
+// The server certificate is not verified by CAs, so a self-signed certificate can be used,
+// but it is checked against the known hosts instead.
+func (k *KnownHosts) ClientTLSConfig(hostPort string) *tls.Config {
+	return &tls.Config{
+		InsecureSkipVerify:    true,
+		VerifyPeerCertificate: k.VerifyPeerCertificate(hostPort),
+		VerifyConnection:      k.VerifyConnection(hostPort),
+		NextProtos:            []string{NextProto},
+		MinVersion:            tls.VersionTLS13,
+	}
+}
+
+// check checks the fingerprint received from the server of the host and port against the pinned fingerprints.
+// If the server is unknown and trust on first use is enabled, it is accepted, and pinned if pin is true.
+// The caller must hold the mutex.
+func (k *KnownHosts) check(hostPort string, fingerprint string, pin bool) error {
+	pinned := k.hosts[hostPort]
+	if len(pinned) == 0 {
+		if !k.trustOnFirstUse {
+			return ErrUnknownHost
+		}
+		if !pin {
+			return nil
+		}
+		if !validHostPort(hostPort) {
+			return ErrInvalidKnownHost
+		}
+		return k.update(hostPort, []string{fingerprint})
+	}
+	for _, pinnedFingerprint := range pinned {
+		if pinnedFingerprint == fingerprint {
+			return nil
+		}
+	}
+	return &HostKeyMismatchError{
+		HostPort: hostPort,
+		Pinned:   append([]string{}, pinned...),
+		Received: fingerprint,
+		Path:     k.path,
+	}
+}
+
+// update replaces the fingerprints of the host and port and writes the known hosts file.
+// If the file cannot be written, the fingerprints of the host and port are restored. The caller must hold the mutex.
+func (k *KnownHosts) update(hostPort string, fingerprints []string) error {
+	previous, ok := k.hosts[hostPort]
+	if len(fingerprints) == 0 {
+		delete(k.hosts, hostPort)
+	} else {
+		k.hosts[hostPort] = fingerprints
+	}
+	err := k.write()
+	if err != nil {
+		if ok {
+			k.hosts[hostPort] = previous
+		} else {
+			delete(k.hosts, hostPort)
+		}
+	}
+	return err
+}
+
+// validHostPort reports whether the host and port can be written to a line of the known hosts file.
+func validHostPort(hostPort string) bool {
+	return hostPort != "" && !strings.ContainsFunc(hostPort, unicode.IsSpace)
+}
+
+// validFingerprint reports whether the fingerprint is a lowercase hex SHA-256 hash. (see Fingerprint)
+func validFingerprint(fingerprint string) bool {
+	if len(fingerprint) != 2*sha256.Size {
+		return false
+	}
+	_, err := hex.DecodeString(fingerprint)
+	return err == nil
+}
+
+// write writes the known hosts to the file. The caller must hold the mutex.
+func (k *KnownHosts) write() error {
+	hostPorts := make([]string, 0, len(k.hosts))
+	for hostPort := range k.hosts {
+		hostPorts = append(hostPorts, hostPort)
+	}
+	sort.Strings(hostPorts)
+
+	buf := bytes.Buffer{}
+	for _, hostPort := range hostPorts {
+		for _, fingerprint := range k.hosts[hostPort] {
+			buf.WriteString(hostPort + " " + fingerprint + "\n")
+		}
+	}
+	return writeFileAtomic(k.path, buf.Bytes())
+}
@@ -0,0 +1,163 @@
+package tls
+
+import (
+	"crypto/tls"
+	"errors"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+)
+
+// DefaultWatchInterval is the interval of Watch used when the interval is not set.
+const DefaultWatchInterval = time.Minute
+
+// Reloader is a certificate source that reloads the key and certificate files without restarting the listener.
+// Set Reloader.GetCertificate to tls.Config.GetCertificate, then new connections use the reloaded certificate
+// while the established connections are kept.
+// A new pair is swapped in only if the key matches the certificate, otherwise the current certificate is kept.
+type Reloader struct {
+	keyPath  string
+	certPath string
+
+	mutex     sync.RWMutex
+	cert      *tls.Certificate
+	stats     [2]fileStat
+	errorFunc func(err error)
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// fileStat is the state of a file used to detect its changes.
+type fileStat struct {
+	modTime time.Time
+	size    int64
+}
+
+// NewReloader creates a new reloader of the key and certificate files. The files must contain a valid pair.
+func NewReloader(keyPath string, certPath string) (*Reloader, error) {
+	r := &Reloader{
+		keyPath:  keyPath,
+		certPath: certPath,
+		closed:   make(chan struct{}),
+	}
+	err := r.Reload()
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// SetErrorFunc sets the callback function called when reloading fails.
+// The callback is called from the goroutines of Watch and ReloadOnSignal, and from Reload.
+func (r *Reloader) SetErrorFunc(errorFunc func(err error)) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.errorFunc = errorFunc
+}
+
+// Certificate returns the current certificate.
+func (r *Reloader) Certificate() *tls.Certificate {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.cert
+}
+
+// GetCertificate returns the current certificate. It is used as tls.Config.GetCertificate of a server.
+func (r *Reloader) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.Certificate(), nil
+}
+
+// GetClientCertificate returns the current certificate. It is used as tls.Config.GetClientCertificate of a client.
+func (r *Reloader) GetClientCertificate(info *tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	return r.Certificate(), nil
+}
+
+// Reload reads the key and certificate files and swaps in the new pair if it is valid.
+// If the files cannot be read or the key does not match the certificate, the current certificate is kept,
+// and the error is reported to the callback of SetErrorFunc and returned.
+func (r *Reloader) Reload() error {
+	stats, _ := r.stat()
+	cert, err := GetSSL(r.keyPath, r.certPath)
+	if err == nil && time.Now().After(cert.Leaf.NotAfter) {
+		err = errors.New("certificate has expired")
+	}
+
+	r.mutex.Lock()
+	r.stats = stats
+	errorFunc := r.errorFunc
+	if err == nil {
+		r.cert = cert
+	}
+	r.mutex.Unlock()
+
+	if err != nil && errorFunc != nil {
+		errorFunc(err)
+	}
+	return err
+}
+
+// Watch checks the key and certificate files every interval in a new goroutine,
+// and reloads them when their modification time or size changes, until the reloader is closed.
+// If the interval is 0 or less, DefaultWatchInterval is used.
+func (r *Reloader) Watch(interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				stats, err := r.stat()
+				r.mutex.RLock()
+				changed := err == nil && stats != r.stats
+				r.mutex.RUnlock()
+				if changed {
+					r.Reload()
+				}
+			case <-r.closed:
+				return
+			}
+		}
+	}()
+}
+
+// ReloadOnSignal reloads the key and certificate files whenever one of the signals is received in a new goroutine,
+// until the reloader is closed. For example, pass syscall.SIGHUP on Unix.
+func (r *Reloader) ReloadOnSignal(signals ...os.Signal) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, signals...)
+	go func() {
+		defer signal.Stop(ch)
+		for {
+			select {
+			case <-ch:
+				r.Reload()
+			case <-r.closed:
+				return
+			}
+		}
+	}()
+}
+
+// Close stops watching the files and the signals.
+func (r *Reloader) Close() error {
+	r.closeOnce.Do(func() {
+		close(r.closed)
+	})
+	return nil
+}
+
+func (r *Reloader) stat() ([2]fileStat, error) {
+	stats := [2]fileStat{}
+	for i, filePath := range []string{r.keyPath, r.certPath} {
+		stat, err := os.Stat(filePath)
+		if err != nil {
+			return stats, err
+		}
+		stats[i] = fileStat{modTime: stat.ModTime(), size: stat.Size()}
+	}
+	return stats, nil
+}
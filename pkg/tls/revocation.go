@@ -0,0 +1,115 @@
+package tls
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrCertificateRevoked is returned when a peer certificate is revoked.
+var ErrCertificateRevoked = errors.New("certificate is revoked")
+
+// ErrCRLExpired is returned when the certificate revocation list of the issuer of a peer certificate is past its next update.
+// The certificates of the issuer are not accepted until a new CRL is added, because they may have been revoked since.
+var ErrCRLExpired = errors.New("certificate revocation list is expired")
+
+// Revocation is a list of revoked certificates checked when a connection is accepted. (see Admission.Revocation)
+// Certificates are revoked by the certificate revocation lists (CRLs) of their issuers or by their SPKI fingerprints.
+// It can be changed at any time.
+type Revocation struct {
+	mutex        sync.RWMutex
+	crls         []*x509.RevocationList
+	fingerprints map[string]bool
+}
+
+// NewRevocation creates a new empty revocation list.
+func NewRevocation() *Revocation {
+	return &Revocation{
+		fingerprints: make(map[string]bool),
+	}
+}
+
+// LoadCRL loads the PEM or DER encoded certificate revocation list of the file.
+// If the issuer is not nil, the signature of the CRL is checked with the issuer certificate.
+func (r *Revocation) LoadCRL(crlPath string, issuer *x509.Certificate) error {
+	crlBytes, err := os.ReadFile(crlPath)
+	if err != nil {
+		return err
+	}
+	block, _ := pem.Decode(crlBytes)
+	if block != nil {
+		crlBytes = block.Bytes
+	}
+	crl, err := x509.ParseRevocationList(crlBytes)
+	if err != nil {
+		return err
+	}
+	if issuer != nil {
+		err = crl.CheckSignatureFrom(issuer)
+		if err != nil {
+			return err
+		}
+	}
+	r.AddCRL(crl)
+	return nil
+}
+
+// AddCRL adds the certificate revocation list.
+// A CRL of the same issuer added before is replaced.
+func (r *Revocation) AddCRL(crl *x509.RevocationList) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for i, added := range r.crls {
+		if bytes.Equal(added.RawIssuer, crl.RawIssuer) {
+			r.crls[i] = crl
+			return
+		}
+	}
+	r.crls = append(r.crls, crl)
+}
+
+// Ban revokes the certificates with the SPKI fingerprint. (see Fingerprint)
+func (r *Revocation) Ban(fingerprint string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.fingerprints[strings.ToLower(fingerprint)] = true
+}
+
+// Unban removes the SPKI fingerprint from the ban list.
+func (r *Revocation) Unban(fingerprint string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.fingerprints, strings.ToLower(fingerprint))
+}
+
+// Check returns ErrCertificateRevoked if any of the certificates is revoked.
+// It returns ErrCRLExpired if the CRL of the issuer of any of the certificates is past its next update.
+func (r *Revocation) Check(certs []*x509.Certificate) error {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	now := time.Now()
+	for _, cert := range certs {
+		if r.fingerprints[Fingerprint(cert)] {
+			return ErrCertificateRevoked
+		}
+		for _, crl := range r.crls {
+			if !bytes.Equal(crl.RawIssuer, cert.RawIssuer) {
+				continue
+			}
+			if !crl.NextUpdate.IsZero() && now.After(crl.NextUpdate) {
+				return ErrCRLExpired
+			}
+			for _, entry := range crl.RevokedCertificateEntries {
+				if entry.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+					return ErrCertificateRevoked
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -1,24 +1,81 @@
 package tls
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rand"
-	"crypto/rsa"
 	"crypto/tls"
 	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/pem"
+	"errors"
 	"math/big"
+	"net"
 	"os"
+	"path/filepath"
+	"time"
 )
 
+// KeyType is the type of the key of a generated certificate.
+type KeyType int
+
+const (
+	// KeyECDSAP256 is an ECDSA key on the P-256 curve.
+	KeyECDSAP256 KeyType = iota
+	// KeyEd25519 is an Ed25519 key.
+	KeyEd25519
+)
+
+// CertificateOptions are the options of a generated certificate.
+type CertificateOptions struct {
+	// KeyType is the type of the key.
+	KeyType KeyType
+	// CommonName is the common name of the subject.
+	CommonName string
+	// Hosts are the host names and IP addresses of the subject alternative names (SANs).
+	Hosts []string
+	// NotBefore is the start of the validity window.
+	// Zero means an hour ago, to allow for clock skew between peers, and the validity is counted from now.
+	NotBefore time.Time
+	// Validity is the length of the validity window from NotBefore, or from now if NotBefore is zero.
+	Validity time.Duration
+}
+
+// DefaultCertificateOptions are the options of the certificates generated by GetCertificate.
+// The hosts are the local host names and addresses.
+var DefaultCertificateOptions = CertificateOptions{
+	KeyType:    KeyECDSAP256,
+	CommonName: "quics-protocol",
+	Hosts:      []string{"localhost", "127.0.0.1", "::1"},
+	Validity:   365 * 24 * time.Hour,
+}
+
+// GetCertificate returns the certificate of the key and certificate files.
+// If both files do not exist, a new certificate is generated with DefaultCertificateOptions
+// and written to the files, so the same certificate is reused on later starts.
+// If both paths are empty, a new certificate is generated without being written.
 func GetCertificate(keyPath string, certPath string) ([]tls.Certificate, error) {
+	return GetCertificateWithOptions(keyPath, certPath, DefaultCertificateOptions)
+}
+
+// GetCertificateWithOptions is the same as GetCertificate, except that a new certificate is generated with the options.
+func GetCertificateWithOptions(keyPath string, certPath string, opts CertificateOptions) ([]tls.Certificate, error) {
 	_, keyErr := os.Stat(keyPath)
 	_, certErr := os.Stat(certPath)
 	cert, err := &tls.Certificate{}, error(nil)
 	if os.IsNotExist(keyErr) && os.IsNotExist(certErr) {
-		cert, err = GenerateSSL()
+		cert, err = GenerateCertificate(opts)
 		if err != nil {
 			return nil, err
 		}
+		if keyPath != "" || certPath != "" {
+			err = WriteCertificate(cert, keyPath, certPath)
+			if err != nil {
+				return nil, err
+			}
+		}
 	} else {
 		cert, err = GetSSL(keyPath, certPath)
 		if err != nil {
@@ -29,32 +86,176 @@ func GetCertificate(keyPath string, certPath string) ([]tls.Certificate, error)
 	return []tls.Certificate{*cert}, nil
 }
 
+// GenerateSSL generates a new self-signed certificate with DefaultCertificateOptions.
 func GenerateSSL() (*tls.Certificate, error) {
-	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	return GenerateCertificate(DefaultCertificateOptions)
+}
+
+// GenerateCertificate generates a new self-signed certificate with the options.
+// The serial number is random, and the certificate can be used for both server and client authentication.
+func GenerateCertificate(opts CertificateOptions) (*tls.Certificate, error) {
+	key, err := GenerateKey(opts.KeyType)
 	if err != nil {
 		return nil, err
 	}
-	template := x509.Certificate{SerialNumber: big.NewInt(1)}
-	certDER, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	template, err := newTemplate(opts)
 	if err != nil {
 		return nil, err
 	}
-	keyOut := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
-	certOut := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER})
+	template.KeyUsage = x509.KeyUsageDigitalSignature
+	template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}
 
-	cert, err := tls.X509KeyPair(certOut, keyOut)
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
 	if err != nil {
 		return nil, err
 	}
+	return newCertificate([][]byte{certDER}, key)
+}
 
-	return &cert, nil
+// GenerateKey generates a new private key of the key type.
+func GenerateKey(keyType KeyType) (crypto.Signer, error) {
+	switch keyType {
+	case KeyECDSAP256:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case KeyEd25519:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		return key, err
+	default:
+		return nil, errors.New("unknown key type")
+	}
 }
 
+// newTemplate creates a certificate template with a random serial number, the validity window and the SANs of the options.
+func newTemplate(opts CertificateOptions) (*x509.Certificate, error) {
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+	if opts.Validity <= 0 {
+		return nil, errors.New("validity of the certificate must be positive")
+	}
+	notBefore, notAfter := opts.NotBefore, opts.NotBefore.Add(opts.Validity)
+	if notBefore.IsZero() {
+		now := time.Now()
+		notBefore, notAfter = now.Add(-time.Hour), now.Add(opts.Validity)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          serialNumber,
+		Subject:               pkix.Name{CommonName: opts.CommonName},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		BasicConstraintsValid: true,
+	}
+	for _, host := range opts.Hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+	return template, nil
+}
+
+// newCertificate creates a certificate of the DER encoded chain and the key, with the parsed leaf.
+func newCertificate(chain [][]byte, key crypto.Signer) (*tls.Certificate, error) {
+	leaf, err := x509.ParseCertificate(chain[0])
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Certificate{
+		Certificate: chain,
+		PrivateKey:  key,
+		Leaf:        leaf,
+	}, nil
+}
+
+// WriteCertificate writes the PEM encoded key and certificate chain to the files with 0600 permissions.
+// Each file is written to a temporary file first and renamed, so the files are never left partially written.
+func WriteCertificate(cert *tls.Certificate, keyPath string, certPath string) error {
+	keyDER, err := x509.MarshalPKCS8PrivateKey(cert.PrivateKey)
+	if err != nil {
+		return err
+	}
+	keyOut := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+	certOut := []byte{}
+	for _, certDER := range cert.Certificate {
+		certOut = append(certOut, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER})...)
+	}
+
+	err = writeFileAtomic(keyPath, keyOut)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(certPath, certOut)
+}
+
+// writeFileAtomic writes the data to a temporary file with 0600 permissions next to the file path and renames it to the file path.
+func writeFileAtomic(filePath string, data []byte) error {
+	dir, name := filepath.Split(filePath)
+	if dir == "" {
+		dir = "."
+	}
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "."+name+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	err = tmp.Chmod(0600)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	_, err = tmp.Write(data)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	err = tmp.Sync()
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filePath)
+}
+
+// GetSSL loads the certificate of the key and certificate files.
 func GetSSL(keyPath string, certPath string) (*tls.Certificate, error) {
 	cert, err := tls.LoadX509KeyPair(certPath, keyPath)
 	if err != nil {
 		return nil, err
 	}
+	if cert.Leaf == nil {
+		cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	return &cert, nil
 }
+
+// ExpiresIn returns how soon the certificate expires. It is negative if the certificate has already expired.
+func ExpiresIn(cert *tls.Certificate) (time.Duration, error) {
+	leaf := cert.Leaf
+	if leaf == nil {
+		if len(cert.Certificate) == 0 {
+			return 0, ErrNoCertificate
+		}
+		var err error
+		leaf, err = x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			return 0, err
+		}
+	}
+	return time.Until(leaf.NotAfter), nil
+}
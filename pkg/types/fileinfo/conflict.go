@@ -0,0 +1,196 @@
+package fileinfo
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ConflictPolicy decides what to do when the file to be replaced has diverged from the expected base state.
+type ConflictPolicy int
+
+const (
+	// ConflictOverwrite replaces the local file with the received file. This is the default.
+	ConflictOverwrite ConflictPolicy = iota
+	// ConflictFail keeps the local file and returns a *ConflictError.
+	ConflictFail
+	// ConflictKeepBoth keeps the local file and writes the received file to a new copy next to it. (see ConflictCopyPath)
+	ConflictKeepBoth
+	// ConflictNewestWins keeps the file with the later modification time.
+	ConflictNewestWins
+	// ConflictKeepLocal keeps the local file and discards the received file.
+	ConflictKeepLocal
+)
+
+// BaseState is the state of the local file that the sender last saw.
+// The local file is in conflict if it does not match the base state any more.
+type BaseState struct {
+	// Missing means that the file did not exist.
+	Missing bool
+	// ModTime is compared when it is not zero.
+	ModTime time.Time
+	// Size is compared when it is not nil.
+	Size *int64
+	// Hash is the SHA-256 hash of the file content and is compared when it is not nil.
+	Hash []byte
+}
+
+// NewBaseState returns the base state with the modification time and size of the file metadata.
+// If fileInfo is nil, the file is expected not to exist.
+func NewBaseState(fileInfo *FileInfo) *BaseState {
+	if fileInfo == nil {
+		return &BaseState{Missing: true}
+	}
+	size := fileInfo.Size
+	return &BaseState{
+		ModTime: fileInfo.ModTime,
+		Size:    &size,
+	}
+}
+
+// ConflictError is returned when the local file has diverged from the expected base state
+// and the conflict policy is ConflictFail.
+type ConflictError struct {
+	// Path of the local file.
+	Path string
+	// Expected is the base state given by the caller.
+	Expected *BaseState
+	// Local is the metadata of the local file. It is nil if the local file does not exist.
+	Local os.FileInfo
+	// Received is the metadata of the received file.
+	Received *FileInfo
+	// CopyPath is the path the received file is written to with ConflictKeepBoth.
+	// It is set after the copy is created, so it is empty when the conflict resolver is called.
+	CopyPath string
+}
+
+func (e *ConflictError) Error() string {
+	if e.Local == nil {
+		return fmt.Sprintf("conflict: %s was removed after the base state", e.Path)
+	}
+	return fmt.Sprintf("conflict: %s was modified after the base state", e.Path)
+}
+
+// maxConflictCopies is the maximum number of copies of a file kept in the same second.
+const maxConflictCopies = 1000
+
+// ConflictCopyPath returns the path the received file is written to when both files are kept.
+// For example, "dir/name.txt" becomes "dir/name.conflict-20060102-150405.txt".
+// If the path already exists, a number is appended, like "dir/name.conflict-20060102-150405-1.txt".
+func ConflictCopyPath(filePath string, now time.Time) string {
+	return conflictCopyPath(filePath, now, 0)
+}
+
+func conflictCopyPath(filePath string, now time.Time, n int) string {
+	dir, name := filepath.Split(filePath)
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext) + ".conflict-" + now.Format("20060102-150405")
+	if n > 0 {
+		base += "-" + strconv.Itoa(n)
+	}
+	return filepath.Join(dir, base+ext)
+}
+
+// createConflictCopy creates an empty file at an unused conflict copy path of the file path and returns the path.
+// The file is created exclusively, so an existing file, like another copy made in the same second, is never replaced.
+func createConflictCopy(filePath string) (string, error) {
+	now := time.Now()
+	for n := 0; n < maxConflictCopies; n++ {
+		copyPath := conflictCopyPath(filePath, now, n)
+		file, err := os.OpenFile(copyPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		return copyPath, file.Close()
+	}
+	return "", fmt.Errorf("too many conflict copies of %s", filePath)
+}
+
+// detectConflict compares the local file with the base state and returns a *ConflictError if they are not matched.
+func (f *FileInfo) detectConflict(filePath string, base *BaseState) (*ConflictError, error) {
+	conflict := &ConflictError{
+		Path:     filePath,
+		Expected: base,
+		Received: f,
+	}
+
+	local, err := os.Stat(filePath)
+	if os.IsNotExist(err) {
+		if base.Missing {
+			return nil, nil
+		}
+		return conflict, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	conflict.Local = local
+	if base.Missing {
+		return conflict, nil
+	}
+
+	if !base.ModTime.IsZero() && !base.ModTime.Equal(local.ModTime()) {
+		return conflict, nil
+	}
+	if base.Size != nil && *base.Size != local.Size() {
+		return conflict, nil
+	}
+	if base.Hash != nil {
+		file, err := os.Open(filePath)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		hash := sha256.New()
+		_, err = io.Copy(hash, file)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(base.Hash, hash.Sum(nil)) {
+			return conflict, nil
+		}
+	}
+	return nil, nil
+}
+
+// resolveConflict returns what to do with the received file, which is one of
+// ConflictOverwrite, ConflictKeepBoth and ConflictKeepLocal, and the conflict if there is one.
+func (f *FileInfo) resolveConflict(filePath string, opts *WriteOptions) (ConflictPolicy, *ConflictError, error) {
+	if opts == nil || opts.ExpectedBase == nil {
+		return ConflictOverwrite, nil, nil
+	}
+	conflict, err := f.detectConflict(filePath, opts.ExpectedBase)
+	if err != nil {
+		return 0, nil, err
+	}
+	if conflict == nil {
+		return ConflictOverwrite, nil, nil
+	}
+
+	policy := opts.ConflictPolicy
+	if opts.ConflictResolver != nil {
+		policy = opts.ConflictResolver(conflict)
+	}
+	switch policy {
+	case ConflictOverwrite, ConflictKeepBoth, ConflictKeepLocal:
+		return policy, conflict, nil
+	case ConflictFail:
+		return 0, nil, conflict
+	case ConflictNewestWins:
+		if conflict.Local == nil || f.ModTime.After(conflict.Local.ModTime()) {
+			return ConflictOverwrite, conflict, nil
+		}
+		return ConflictKeepLocal, conflict, nil
+	default:
+		return 0, nil, fmt.Errorf("unknown conflict policy %d", policy)
+	}
+}
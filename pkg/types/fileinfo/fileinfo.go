@@ -1,10 +1,9 @@
 package fileinfo
 
 import (
-	"errors"
 	"io"
 	"os"
-	"path/filepath"
+	"sort"
 	"time"
 
 	pb "github.com/quic-s/quics-protocol/proto/v1"
@@ -19,6 +18,23 @@ type FileInfo struct {
 	Mode    os.FileMode
 	ModTime time.Time
 	IsDir   bool
+
+	// Optional metadata. Metadata is the set of the optional metadata sent by the sending side.
+	Metadata Metadata
+	Uid      int
+	Gid      int
+	User     string
+	Group    string
+	Xattrs   map[string][]byte
+	// SymlinkTarget is the target of the symbolic link.
+	SymlinkTarget string
+	// HardlinkTarget is the name of an earlier entry of the same directory transfer that this file is hard linked to.
+	HardlinkTarget string
+	// Rdev is the device number of the device file.
+	Rdev uint64
+	// Sparse is true when only the data extents of the file are sent. The rest of the file is holes.
+	Sparse  bool
+	Extents []Extent
 }
 
 // Create new FileInfo instance from os.FileInfo.
@@ -47,6 +63,35 @@ func NewFromProtobuf(src *pb.FileInfo) (*FileInfo, error) {
 		Mode:    os.FileMode(src.Mode),
 		ModTime: modTime,
 		IsDir:   src.IsDir,
+
+		Metadata:       Metadata(src.Metadata),
+		Uid:            int(src.Uid),
+		Gid:            int(src.Gid),
+		User:           src.User,
+		Group:          src.Group,
+		SymlinkTarget:  src.SymlinkTarget,
+		HardlinkTarget: src.HardlinkTarget,
+		Rdev:           src.Rdev,
+	}
+	if src.Sparse {
+		fileInfo.Sparse = true
+		fileInfo.Extents = make([]Extent, 0, len(src.Extents))
+		for _, extent := range src.Extents {
+			fileInfo.Extents = append(fileInfo.Extents, Extent{
+				Offset: extent.Offset,
+				Length: extent.Length,
+			})
+		}
+		err = fileInfo.validateExtents()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(src.Xattrs) > 0 {
+		fileInfo.Xattrs = make(map[string][]byte, len(src.Xattrs))
+		for _, xattr := range src.Xattrs {
+			fileInfo.Xattrs[xattr.Name] = xattr.Value
+		}
 	}
 
 	return fileInfo, nil
@@ -65,6 +110,33 @@ func (f *FileInfo) ToProtobuf() (*pb.FileInfo, error) {
 		Mode:    int32(f.Mode),
 		ModTime: gobModTime,
 		IsDir:   f.IsDir,
+
+		Metadata:       uint32(f.Metadata),
+		Uid:            uint32(f.Uid),
+		Gid:            uint32(f.Gid),
+		User:           f.User,
+		Group:          f.Group,
+		SymlinkTarget:  f.SymlinkTarget,
+		HardlinkTarget: f.HardlinkTarget,
+		Rdev:           f.Rdev,
+		Sparse:         f.Sparse,
+	}
+	for _, extent := range f.Extents {
+		fileInfo.Extents = append(fileInfo.Extents, &pb.Extent{
+			Offset: extent.Offset,
+			Length: extent.Length,
+		})
+	}
+	names := make([]string, 0, len(f.Xattrs))
+	for name := range f.Xattrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fileInfo.Xattrs = append(fileInfo.Xattrs, &pb.Xattr{
+			Name:  name,
+			Value: f.Xattrs[name],
+		})
 	}
 	return fileInfo, nil
 }
@@ -72,73 +144,9 @@ func (f *FileInfo) ToProtobuf() (*pb.FileInfo, error) {
 // WriteFileWithInfo writes the file with metadata to the disk.
 // The file path and file data(io.Reader type) need to be passed as parameters.
 // This method creates a directory if the directory does not exist or received file is directory.
-// If the file already exists, it will be overwritten.
+// If the file already exists, it will be replaced atomically.
+// The file content is written to a temporary file in the same directory first,
+// so the existing file is kept intact if writing the file content fails.
 func (f *FileInfo) WriteFileWithInfo(filePath string, fileContent io.Reader) error {
-	// When the file is a directory, create the directory and return.
-	if f.IsDir {
-		err := os.MkdirAll(filePath, f.Mode)
-		if err != nil {
-			return err
-		}
-		file, err := os.Open(filePath)
-		if err != nil {
-			return err
-		}
-		defer file.Close()
-
-		// Set file metadata.
-		err = file.Chmod(f.Mode)
-		if err != nil {
-			return err
-		}
-		err = os.Chtimes(filePath, time.Now(), f.ModTime)
-		if err != nil {
-			return err
-		}
-		return nil
-	}
-
-	// When the file is not a directory, create the file and write the file content.
-
-	// Open file with O_TRUNC flag to overwrite the file when the file already exists.
-	file, err := os.OpenFile(filePath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, f.Mode)
-	if err != nil {
-		// If the file does not exist, create the file.
-		if os.IsNotExist(err) {
-			dir, _ := filepath.Split(filePath)
-			if dir != "" {
-				err := os.MkdirAll(dir, 0700)
-				if err != nil {
-					return err
-				}
-			}
-			file, err = os.Create(filePath)
-			if err != nil {
-				return err
-			}
-		} else {
-			return err
-		}
-	}
-	defer file.Close()
-
-	// Write file content.
-	n, err := io.Copy(file, fileContent)
-	if err != nil {
-		return err
-	}
-	if n != f.Size {
-		return errors.New("file content size is not equal with fileinfo.size")
-	}
-
-	// Set file metadata.
-	err = file.Chmod(f.Mode)
-	if err != nil {
-		return err
-	}
-	err = os.Chtimes(filePath, time.Now(), f.ModTime)
-	if err != nil {
-		return err
-	}
-	return nil
+	return f.WriteFileWithOptions(filePath, fileContent, nil)
 }
@@ -0,0 +1,123 @@
+package fileinfo
+
+import (
+	"errors"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+)
+
+// Metadata is a set of flags for the optional file metadata.
+// The sending side chooses which metadata to send, and the receiving side chooses which metadata to apply.
+type Metadata uint32
+
+const (
+	// MetadataOwner is the numeric user and group ID of the file.
+	MetadataOwner Metadata = 1 << iota
+	// MetadataOwnerName is the user and group name of the file.
+	// When it is applied, the names are resolved on the receiving side and take precedence over the numeric IDs.
+	MetadataOwnerName
+	// MetadataXattr is the extended attributes of the file.
+	MetadataXattr
+	// MetadataSymlink sends symbolic links as links instead of following them.
+	MetadataSymlink
+	// MetadataHardlink sends the files hard linked to an earlier file of the same directory transfer as links.
+	MetadataHardlink
+	// MetadataSpecial sends named pipes and device files.
+	MetadataSpecial
+	// MetadataSparse sends only the data extents of sparse files, so the holes are not sent.
+	MetadataSparse
+
+	// MetadataAll is all of the optional metadata.
+	MetadataAll = MetadataOwner | MetadataOwnerName | MetadataXattr | MetadataSymlink | MetadataHardlink | MetadataSpecial | MetadataSparse
+)
+
+// ErrMetadataNotSupported is returned when the metadata cannot be read or applied on this platform.
+var ErrMetadataNotSupported = errors.New("file metadata is not supported on this platform")
+
+// NewFromPath creates a new FileInfo instance from the file at the file path with the optional metadata.
+// If MetadataSymlink is set, a symbolic link is not followed and its target is set to SymlinkTarget.
+// Symbolic links and special files are sent without content, so their size is 0.
+func NewFromPath(filePath string, metadata Metadata) (*FileInfo, error) {
+	var src os.FileInfo
+	var err error
+	if metadata&MetadataSymlink != 0 {
+		src, err = os.Lstat(filePath)
+	} else {
+		src, err = os.Stat(filePath)
+	}
+	if err != nil {
+		return nil, err
+	}
+	fileInfo, err := NewFromOSFileInfo(src)
+	if err != nil {
+		return nil, err
+	}
+	fileInfo.Name = filepath.Base(filePath)
+	fileInfo.Metadata = metadata
+
+	if src.Mode()&os.ModeSymlink != 0 {
+		fileInfo.SymlinkTarget, err = os.Readlink(filePath)
+		if err != nil {
+			return nil, err
+		}
+		fileInfo.Size = 0
+	}
+	if fileInfo.IsSpecial() {
+		if metadata&MetadataSpecial == 0 {
+			return nil, errors.New("special file is not sent without MetadataSpecial: " + filePath)
+		}
+		fileInfo.Size = 0
+	}
+
+	err = fileInfo.readMetadata(filePath, src)
+	if err != nil {
+		return nil, err
+	}
+	if metadata&MetadataOwnerName != 0 {
+		if u, err := user.LookupId(strconv.Itoa(fileInfo.Uid)); err == nil {
+			fileInfo.User = u.Username
+		}
+		if g, err := user.LookupGroupId(strconv.Itoa(fileInfo.Gid)); err == nil {
+			fileInfo.Group = g.Name
+		}
+	}
+	return fileInfo, nil
+}
+
+// IsSymlink reports whether the file is a symbolic link.
+func (f *FileInfo) IsSymlink() bool {
+	return f.Mode&os.ModeSymlink != 0
+}
+
+// IsSpecial reports whether the file is a named pipe or a device file.
+func (f *FileInfo) IsSpecial() bool {
+	return f.Mode&(os.ModeNamedPipe|os.ModeDevice|os.ModeCharDevice) != 0
+}
+
+// HasContent reports whether the file content follows the metadata when it is sent.
+func (f *FileInfo) HasContent() bool {
+	return !f.IsDir && !f.IsSymlink() && !f.IsSpecial() && f.HardlinkTarget == ""
+}
+
+// ownerIDs returns the user and group ID to apply, resolving the names if they are applied.
+func (f *FileInfo) ownerIDs(apply Metadata) (int, int, bool) {
+	uid, gid := -1, -1
+	if apply&MetadataOwner != 0 && f.Metadata&MetadataOwner != 0 {
+		uid, gid = f.Uid, f.Gid
+	}
+	if apply&MetadataOwnerName != 0 && f.Metadata&MetadataOwnerName != 0 {
+		if u, err := user.Lookup(f.User); f.User != "" && err == nil {
+			if id, err := strconv.Atoi(u.Uid); err == nil {
+				uid = id
+			}
+		}
+		if g, err := user.LookupGroup(f.Group); f.Group != "" && err == nil {
+			if id, err := strconv.Atoi(g.Gid); err == nil {
+				gid = id
+			}
+		}
+	}
+	return uid, gid, uid != -1 || gid != -1
+}
@@ -0,0 +1,117 @@
+//go:build linux
+
+package fileinfo
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strconv"
+	"syscall"
+)
+
+// HardlinkKey returns a key identifying the file when it has more than one hard link.
+// Files with the same key are hard links to the same file. It returns an empty string otherwise.
+func HardlinkKey(src os.FileInfo) string {
+	stat, ok := src.Sys().(*syscall.Stat_t)
+	if !ok || stat.Nlink <= 1 || src.IsDir() {
+		return ""
+	}
+	return strconv.FormatUint(stat.Dev, 10) + ":" + strconv.FormatUint(stat.Ino, 10)
+}
+
+// readMetadata reads the optional metadata that is not in os.FileInfo.
+func (f *FileInfo) readMetadata(filePath string, src os.FileInfo) error {
+	stat, ok := src.Sys().(*syscall.Stat_t)
+	if !ok {
+		return ErrMetadataNotSupported
+	}
+	if f.Metadata&(MetadataOwner|MetadataOwnerName) != 0 {
+		f.Uid = int(stat.Uid)
+		f.Gid = int(stat.Gid)
+	}
+	if f.IsSpecial() {
+		f.Rdev = stat.Rdev
+	}
+	// Extended attributes of symbolic links are not supported.
+	if f.Metadata&MetadataXattr != 0 && !f.IsSymlink() {
+		xattrs, err := listXattrs(filePath)
+		if err != nil {
+			return err
+		}
+		f.Xattrs = xattrs
+	}
+	return nil
+}
+
+func listXattrs(filePath string) (map[string][]byte, error) {
+	size, err := syscall.Listxattr(filePath, nil)
+	if err == syscall.ENOTSUP {
+		return nil, nil
+	}
+	if err != nil || size == 0 {
+		return nil, err
+	}
+	names := make([]byte, size)
+	size, err = syscall.Listxattr(filePath, names)
+	if err != nil {
+		return nil, err
+	}
+
+	xattrs := map[string][]byte{}
+	for _, name := range bytes.Split(names[:size], []byte{0}) {
+		if len(name) == 0 {
+			continue
+		}
+		size, err := syscall.Getxattr(filePath, string(name), nil)
+		if err != nil {
+			return nil, err
+		}
+		value := make([]byte, size)
+		size, err = syscall.Getxattr(filePath, string(name), value)
+		if err != nil {
+			return nil, err
+		}
+		xattrs[string(name)] = value[:size]
+	}
+	return xattrs, nil
+}
+
+// applyMetadata applies the optional metadata chosen by the receiving side to the file at the file path.
+func (f *FileInfo) applyMetadata(filePath string, apply Metadata) error {
+	if apply&MetadataXattr != 0 && !f.IsSymlink() {
+		for name, value := range f.Xattrs {
+			err := syscall.Setxattr(filePath, name, value, 0)
+			if err != nil {
+				return &os.PathError{Op: "setxattr", Path: filePath, Err: err}
+			}
+		}
+	}
+	if uid, gid, ok := f.ownerIDs(apply); ok {
+		err := os.Lchown(filePath, uid, gid)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createSpecial creates the named pipe or device file at the file path.
+func (f *FileInfo) createSpecial(filePath string) error {
+	mode := uint32(f.Mode.Perm())
+	switch {
+	case f.Mode&os.ModeNamedPipe != 0:
+		mode |= syscall.S_IFIFO
+	case f.Mode&os.ModeCharDevice != 0:
+		mode |= syscall.S_IFCHR
+	case f.Mode&os.ModeDevice != 0:
+		mode |= syscall.S_IFBLK
+	default:
+		return errors.New("file is not a special file")
+	}
+	err := syscall.Mknod(filePath, mode, int(f.Rdev))
+	if err != nil {
+		return &os.PathError{Op: "mknod", Path: filePath, Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+//go:build !linux
+
+package fileinfo
+
+import (
+	"os"
+)
+
+// HardlinkKey returns a key identifying the file when it has more than one hard link.
+// Hard links are not detected on this platform, so it always returns an empty string.
+func HardlinkKey(src os.FileInfo) string {
+	return ""
+}
+
+// readMetadata reads the optional metadata that is not in os.FileInfo.
+// Only symbolic links and sparse files are supported on this platform, so the other metadata is not sent.
+func (f *FileInfo) readMetadata(filePath string, src os.FileInfo) error {
+	f.Metadata &= MetadataSymlink | MetadataSparse
+	return nil
+}
+
+// applyMetadata applies the optional metadata chosen by the receiving side to the file at the file path.
+func (f *FileInfo) applyMetadata(filePath string, apply Metadata) error {
+	if apply&(MetadataOwner|MetadataOwnerName|MetadataXattr)&f.Metadata != 0 {
+		return ErrMetadataNotSupported
+	}
+	return nil
+}
+
+// createSpecial creates the named pipe or device file at the file path.
+func (f *FileInfo) createSpecial(filePath string) error {
+	return ErrMetadataNotSupported
+}
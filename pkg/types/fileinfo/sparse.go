@@ -0,0 +1,134 @@
+package fileinfo
+
+import (
+	"errors"
+	"io"
+	"sort"
+)
+
+// MaxExtents is the maximum number of data extents sent with a sparse file.
+// If a file has more extents, the extents with the smallest holes between them are merged.
+const MaxExtents = 2048
+
+// Extent is a range of a sparse file that has data. The ranges between extents are holes.
+type Extent struct {
+	Offset int64
+	Length int64
+}
+
+// DataSize returns the number of bytes of the file content sent through the connection.
+// For a sparse file, it is the total length of the data extents. Otherwise, it is the size of the file.
+func (f *FileInfo) DataSize() int64 {
+	if !f.Sparse {
+		return f.Size
+	}
+	size := int64(0)
+	for _, extent := range f.Extents {
+		size += extent.Length
+	}
+	return size
+}
+
+// validateExtents checks that the extents are sorted, not overlapped and within the size of the file.
+func (f *FileInfo) validateExtents() error {
+	end := int64(0)
+	for _, extent := range f.Extents {
+		if extent.Offset < end || extent.Length <= 0 || extent.Offset+extent.Length > f.Size || extent.Offset+extent.Length < extent.Offset {
+			return errors.New("invalid sparse file extents")
+		}
+		end = extent.Offset + extent.Length
+	}
+	return nil
+}
+
+// mergeExtents merges the extents separated by the smallest holes until there are at most MaxExtents extents.
+func mergeExtents(extents []Extent) []Extent {
+	if len(extents) <= MaxExtents {
+		return extents
+	}
+	holes := make([]int64, 0, len(extents)-1)
+	for i := 1; i < len(extents); i++ {
+		holes = append(holes, extents[i].Offset-(extents[i-1].Offset+extents[i-1].Length))
+	}
+	sort.Slice(holes, func(i, j int) bool { return holes[i] < holes[j] })
+	// Holes up to this size are filled, so the number of extents goes under the maximum.
+	threshold := holes[len(extents)-MaxExtents-1]
+
+	merged := []Extent{extents[0]}
+	for _, extent := range extents[1:] {
+		last := &merged[len(merged)-1]
+		if extent.Offset-(last.Offset+last.Length) <= threshold {
+			last.Length = extent.Offset + extent.Length - last.Offset
+			continue
+		}
+		merged = append(merged, extent)
+	}
+	return merged
+}
+
+// SparseReader is the file content of a sparse file.
+// Read returns the whole file content with zeros for the holes, so it can be used like the content of any other file.
+// WriteFileWithInfo uses DataReader to skip the holes and recreate them on the disk.
+type SparseReader interface {
+	io.Reader
+	// DataReader returns the reader of the data extents in order, without the holes.
+	// It must not be used together with Read.
+	DataReader() io.Reader
+}
+
+// NewSparseReader creates the file content of a sparse file from the reader of its data extents.
+func NewSparseReader(data io.Reader, extents []Extent, size int64) SparseReader {
+	return &sparseReader{
+		data:    data,
+		extents: extents,
+		size:    size,
+	}
+}
+
+type sparseReader struct {
+	data    io.Reader
+	extents []Extent
+	size    int64
+	offset  int64
+}
+
+func (r *sparseReader) DataReader() io.Reader {
+	return r.data
+}
+
+func (r *sparseReader) Read(p []byte) (int, error) {
+	if r.offset >= r.size {
+		return 0, io.EOF
+	}
+	// Skip the extents that are already read.
+	for len(r.extents) > 0 && r.extents[0].Offset+r.extents[0].Length <= r.offset {
+		r.extents = r.extents[1:]
+	}
+
+	if len(r.extents) == 0 || r.offset < r.extents[0].Offset {
+		// Fill the hole with zeros.
+		holeEnd := r.size
+		if len(r.extents) > 0 {
+			holeEnd = r.extents[0].Offset
+		}
+		if int64(len(p)) > holeEnd-r.offset {
+			p = p[:holeEnd-r.offset]
+		}
+		for i := range p {
+			p[i] = 0
+		}
+		r.offset += int64(len(p))
+		return len(p), nil
+	}
+
+	extentEnd := r.extents[0].Offset + r.extents[0].Length
+	if int64(len(p)) > extentEnd-r.offset {
+		p = p[:extentEnd-r.offset]
+	}
+	n, err := r.data.Read(p)
+	r.offset += int64(n)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
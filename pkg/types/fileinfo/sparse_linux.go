@@ -0,0 +1,59 @@
+//go:build linux
+
+package fileinfo
+
+import (
+	"errors"
+	"io"
+	"os"
+	"syscall"
+)
+
+const (
+	seekData = 3
+	seekHole = 4
+)
+
+// DataExtents returns the data extents of the file with SEEK_DATA and SEEK_HOLE.
+// It returns nil if the file has no holes or the file system does not support finding them.
+func DataExtents(file *os.File, size int64) ([]Extent, error) {
+	extents := []Extent{}
+	offset := int64(0)
+	for offset < size {
+		start, err := file.Seek(offset, seekData)
+		if errors.Is(err, syscall.ENXIO) {
+			// The rest of the file is a hole.
+			break
+		}
+		if errors.Is(err, syscall.EINVAL) || errors.Is(err, syscall.EOPNOTSUPP) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		end, err := file.Seek(start, seekHole)
+		if err != nil {
+			return nil, err
+		}
+		if end > size {
+			end = size
+		}
+		if start >= end {
+			break
+		}
+		extents = append(extents, Extent{Offset: start, Length: end - start})
+		offset = end
+	}
+
+	_, err := file.Seek(0, io.SeekStart)
+	if err != nil {
+		return nil, err
+	}
+	if len(extents) == 1 && extents[0].Offset == 0 && extents[0].Length == size {
+		return nil, nil
+	}
+	if len(extents) == 0 && size == 0 {
+		return nil, nil
+	}
+	return mergeExtents(extents), nil
+}
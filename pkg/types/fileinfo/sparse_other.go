@@ -0,0 +1,13 @@
+//go:build !linux
+
+package fileinfo
+
+import (
+	"os"
+)
+
+// DataExtents returns the data extents of the file.
+// Holes are not detected on this platform, so it always returns nil.
+func DataExtents(file *os.File, size int64) ([]Extent, error) {
+	return nil, nil
+}
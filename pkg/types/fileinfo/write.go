@@ -0,0 +1,359 @@
+package fileinfo
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+)
+
+// DefaultBackupSuffix is appended to the file path to name the backup when WriteOptions.BackupSuffix is empty.
+const DefaultBackupSuffix = "~"
+
+// WriteOptions are options for writing a received file to the disk.
+type WriteOptions struct {
+	// Backup keeps the previous version of the file as a backup before it is replaced.
+	Backup bool
+	// BackupSuffix is appended to the file path to name the backup.
+	// If it is empty, DefaultBackupSuffix is used.
+	BackupSuffix string
+
+	// ExpectedBase is the state of the file path that the received file is based on.
+	// If it is not nil and the local file does not match it, the conflict is resolved by ConflictPolicy.
+	ExpectedBase *BaseState
+	// ConflictPolicy decides what to do on a conflict. The default is ConflictOverwrite.
+	ConflictPolicy ConflictPolicy
+	// ConflictResolver is called on a conflict and returns the policy for it instead of ConflictPolicy.
+	ConflictResolver func(conflict *ConflictError) ConflictPolicy
+	// KeepBothFunc is called with the conflict after the received file is written to conflict.CopyPath by ConflictKeepBoth.
+	KeepBothFunc func(conflict *ConflictError)
+
+	// Apply is the set of the optional metadata to apply to the file.
+	// The metadata is applied only when the sending side sent it.
+	// Symbolic links and special files are skipped unless MetadataSymlink and MetadataSpecial are applied.
+	Apply Metadata
+}
+
+func (opts *WriteOptions) apply() Metadata {
+	if opts == nil {
+		return 0
+	}
+	return opts.Apply & MetadataAll
+}
+
+// WriteFileWithOptions writes the file with metadata to the disk like WriteFileWithInfo with the options.
+// The file content is written to a temporary file in the same directory and synced to the disk.
+// After the metadata is set, the temporary file is renamed over the file path and the directory is synced,
+// so the file path always has either the previous file or the complete new file.
+// If writing the file content fails, the temporary file is removed.
+// If opts.ExpectedBase is set, the local file is checked for a conflict just before it is replaced.
+func (f *FileInfo) WriteFileWithOptions(filePath string, fileContent io.Reader, opts *WriteOptions) error {
+	// When the file is a directory, create the directory and return.
+	if f.IsDir {
+		err := os.MkdirAll(filePath, f.Mode)
+		if err != nil {
+			return err
+		}
+		file, err := os.Open(filePath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		// Set file metadata.
+		err = file.Chmod(f.Mode)
+		if err != nil {
+			return err
+		}
+		err = f.applyMetadata(filePath, opts.apply())
+		if err != nil {
+			return err
+		}
+		err = os.Chtimes(filePath, time.Now(), f.ModTime)
+		if err != nil {
+			return err
+		}
+		return nil
+	}
+	if f.IsSymlink() || f.IsSpecial() {
+		return f.writeLink(filePath, opts)
+	}
+
+	// When the file is not a directory, write the file content to a temporary file next to the file path.
+	dir, name := filepath.Split(filePath)
+	if dir != "" {
+		err := os.MkdirAll(dir, 0700)
+		if err != nil {
+			return err
+		}
+	}
+	tmp, err := os.CreateTemp(dir, "."+name+".tmp-*")
+	if err != nil {
+		return err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	// Write file content.
+	if sparse, ok := fileContent.(SparseReader); ok && f.Sparse {
+		err = f.writeSparse(tmp, sparse.DataReader())
+		if err != nil {
+			return err
+		}
+	} else {
+		n, err := io.Copy(tmp, fileContent)
+		if err != nil {
+			return err
+		}
+		if n != f.Size {
+			return errors.New("file content size is not equal with fileinfo.size")
+		}
+	}
+
+	err = f.commit(tmp, filePath, opts)
+	if err != nil {
+		return err
+	}
+	committed = true
+	return nil
+}
+
+// CommitFile moves a fully written temporary file to the file path and sets its metadata.
+// The temporary file must be on the same file system as the file path, e.g. in the same directory.
+// The temporary file is synced to the disk before it is renamed, and the directory is synced after it.
+// If the file already exists, it will be replaced atomically.
+// If the local file is kept by the conflict policy, the temporary file is removed.
+func (f *FileInfo) CommitFile(tmpPath string, filePath string, opts *WriteOptions) error {
+	tmp, err := os.OpenFile(tmpPath, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	return f.commit(tmp, filePath, opts)
+}
+
+// writeSparse writes only the data extents to the file, so the holes between them are left unallocated.
+// If the file system does not support sparse files, the holes are filled with zeros by the file system.
+func (f *FileInfo) writeSparse(file *os.File, data io.Reader) error {
+	for _, extent := range f.Extents {
+		_, err := file.Seek(extent.Offset, io.SeekStart)
+		if err != nil {
+			return err
+		}
+		n, err := io.CopyN(file, data, extent.Length)
+		if err != nil {
+			return err
+		}
+		if n != extent.Length {
+			return errors.New("file content size is not equal with fileinfo.extents")
+		}
+	}
+	// Truncate the file to its size, so a hole at the end of the file is created.
+	return file.Truncate(f.Size)
+}
+
+// WriteHardlink writes the file as a hard link to the existing file, which is an earlier entry of the same transfer.
+// If MetadataHardlink is not applied, the content of the existing file is copied instead.
+func (f *FileInfo) WriteHardlink(existingPath string, filePath string, opts *WriteOptions) error {
+	if opts.apply()&MetadataHardlink == 0 {
+		existing, err := os.Open(existingPath)
+		if err != nil {
+			return err
+		}
+		defer existing.Close()
+		return f.WriteFileWithOptions(filePath, existing, opts)
+	}
+
+	tmpPath, err := tempPath(filePath)
+	if err != nil {
+		return err
+	}
+	err = os.Link(existingPath, tmpPath)
+	if err != nil {
+		return err
+	}
+	return f.replace(tmpPath, filePath, opts)
+}
+
+// writeLink creates the symbolic link or special file if it is applied. Otherwise, it is skipped.
+func (f *FileInfo) writeLink(filePath string, opts *WriteOptions) error {
+	apply := opts.apply()
+	if f.IsSymlink() && apply&MetadataSymlink == 0 || f.IsSpecial() && apply&MetadataSpecial == 0 {
+		return nil
+	}
+
+	tmpPath, err := tempPath(filePath)
+	if err != nil {
+		return err
+	}
+	if f.IsSymlink() {
+		err = os.Symlink(f.SymlinkTarget, tmpPath)
+	} else {
+		err = f.createSpecial(tmpPath)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = f.applyMetadata(tmpPath, apply)
+	if err == nil && f.IsSpecial() {
+		err = os.Chmod(tmpPath, f.Mode)
+		if err == nil {
+			err = os.Chtimes(tmpPath, time.Now(), f.ModTime)
+		}
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return f.replace(tmpPath, filePath, opts)
+}
+
+// tempPath returns an unused temporary path next to the file path.
+func tempPath(filePath string) (string, error) {
+	dir, name := filepath.Split(filePath)
+	if dir != "" {
+		err := os.MkdirAll(dir, 0700)
+		if err != nil {
+			return "", err
+		}
+	}
+	tmp, err := os.CreateTemp(dir, "."+name+".tmp-*")
+	if err != nil {
+		return "", err
+	}
+	tmp.Close()
+	err = os.Remove(tmp.Name())
+	if err != nil {
+		return "", err
+	}
+	return tmp.Name(), nil
+}
+
+// commit syncs and closes the temporary file, sets its metadata and renames it over the file path.
+// The temporary file is closed even if it returns an error.
+func (f *FileInfo) commit(tmp *os.File, filePath string, opts *WriteOptions) error {
+	// Set file metadata. The owner is changed first, because it clears the setuid and setgid bits.
+	err := f.applyMetadata(tmp.Name(), opts.apply())
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	err = tmp.Chmod(f.Mode)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	err = tmp.Sync()
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+	err = os.Chtimes(tmp.Name(), time.Now(), f.ModTime)
+	if err != nil {
+		return err
+	}
+	return f.replace(tmp.Name(), filePath, opts)
+}
+
+// replace renames the temporary path over the file path after resolving a conflict and keeping a backup.
+// If the local file is kept, the temporary path is removed.
+func (f *FileInfo) replace(tmpPath string, filePath string, opts *WriteOptions) error {
+	policy, conflict, err := f.resolveConflict(filePath, opts)
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	switch policy {
+	case ConflictKeepLocal:
+		// The local file is kept, so the received file is discarded.
+		return os.Remove(tmpPath)
+	case ConflictKeepBoth:
+		copyPath, err := createConflictCopy(filePath)
+		if err != nil {
+			os.Remove(tmpPath)
+			return err
+		}
+		// The copy path is created by this transfer, so it can be replaced.
+		err = os.Rename(tmpPath, copyPath)
+		if err != nil {
+			os.Remove(tmpPath)
+			os.Remove(copyPath)
+			return err
+		}
+		err = syncDir(filepath.Dir(copyPath))
+		if err != nil {
+			return err
+		}
+		conflict.CopyPath = copyPath
+		if opts.KeepBothFunc != nil {
+			opts.KeepBothFunc(conflict)
+		}
+		return nil
+	}
+
+	if opts != nil && opts.Backup {
+		err = backup(filePath, opts.BackupSuffix)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = os.Rename(tmpPath, filePath)
+	if err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(filePath))
+}
+
+// backup keeps the existing file at the file path as a backup.
+// A hard link is used, so the file path still exists until the new file is renamed over it.
+func backup(filePath string, suffix string) error {
+	if suffix == "" {
+		suffix = DefaultBackupSuffix
+	}
+	backupPath := filePath + suffix
+
+	_, err := os.Lstat(filePath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(backupPath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	err = os.Link(filePath, backupPath)
+	if err != nil {
+		// Hard links are not supported, so move the file instead.
+		return os.Rename(filePath, backupPath)
+	}
+	return nil
+}
+
+// syncDir syncs the directory, so a renamed file in it is durable.
+func syncDir(dirPath string) error {
+	// Directories cannot be synced on Windows.
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	dir, err := os.Open(dirPath)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
@@ -3,15 +3,25 @@ package qp
 import (
 	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"log"
 	"net"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/quic-go/quic-go"
+	"github.com/quic-s/quics-protocol/pkg/admission"
+	"github.com/quic-s/quics-protocol/pkg/auth"
 	"github.com/quic-s/quics-protocol/pkg/connection"
+	"github.com/quic-s/quics-protocol/pkg/enrollment"
+	qpErr "github.com/quic-s/quics-protocol/pkg/error"
 	qpHandler "github.com/quic-s/quics-protocol/pkg/handler"
 	qpLog "github.com/quic-s/quics-protocol/pkg/log"
+	"github.com/quic-s/quics-protocol/pkg/metrics"
+	"github.com/quic-s/quics-protocol/pkg/ratelimit"
+	qpStream "github.com/quic-s/quics-protocol/pkg/stream"
 )
 
 // QP is a quics-protocol instance.
@@ -19,12 +29,26 @@ import (
 // For more information, see the README.md of the quics-protocol repository.
 // https://github.com/quic-s/quics-protocol
 type QP struct {
-	ctx          context.Context
-	cancel       context.CancelFunc
-	quicConf     *quic.Config
-	quicListener *quic.Listener
-	handler      *qpHandler.Handler
-	logLevel     int
+	ctx             context.Context
+	cancel          context.CancelFunc
+	quicConf        *quic.Config
+	quicListener    *quic.Listener
+	handler         *qpHandler.Handler
+	logLevel        int
+	uploadLimiter   *ratelimit.Limiter
+	downloadLimiter *ratelimit.Limiter
+	mutex           sync.Mutex
+	limits          qpStream.Limits
+	timeouts        qpStream.Timeouts
+	admission       *admission.Controller
+	clientCAs       *x509.CertPool
+	authenticators  []auth.Authenticator
+	enrollment      *enrollment.Enrollment
+	capabilities    Capabilities
+	conns           map[*Connection]struct{}
+	reconnectFunc   func(oldConn *Connection, newConn *Connection, err error)
+	pingInterval    time.Duration
+	metrics         Metrics
 }
 
 // Create new quics-protocol instance with log level (LOG_LEVEL_DEBUG, LOG_LEVEL_INFO, LOG_LEVEL_ERROR)
@@ -37,12 +61,20 @@ func New(logLevel int) (*QP, error) {
 	handler := qpHandler.New(logLevel, ctx, cancel)
 
 	return &QP{
-		ctx:          ctx,
-		cancel:       cancel,
-		quicConf:     quicConf,
-		quicListener: nil,
-		handler:      handler,
-		logLevel:     logLevel,
+		ctx:             ctx,
+		cancel:          cancel,
+		quicConf:        quicConf,
+		quicListener:    nil,
+		handler:         handler,
+		logLevel:        logLevel,
+		uploadLimiter:   ratelimit.New(0, nil),
+		downloadLimiter: ratelimit.New(0, nil),
+		limits:          qpStream.DefaultLimits,
+		timeouts:        qpStream.DefaultTimeouts,
+		admission:       admission.NewController(),
+		capabilities:    connection.DefaultCapabilities,
+		conns:           make(map[*Connection]struct{}),
+		metrics:         metrics.Noop{},
 	}, nil
 }
 
@@ -50,45 +82,11 @@ func New(logLevel int) (*QP, error) {
 // Return connection instance and error.
 // Need to set receive handler using RecvTransactionHandleFunc method before dialing.
 func (q *QP) Dial(host string, port int, tlsConf *tls.Config) (*Connection, error) {
-	if q.logLevel == LOG_LEVEL_DEBUG {
-		q.quicConf.Tracer = qpLog.NewQLogTracer()
-	}
-
-	udpConn, err := net.ListenUDP("udp", nil)
-	if err != nil {
-		return nil, err
-	}
-
-	q.ctx, q.cancel = context.WithTimeout(q.ctx, 10*time.Second)
-
-	ips, err := net.LookupIP(host)
-	if err != nil {
-		return nil, err
-	}
-	if len(ips) < 1 {
-		return nil, errors.New("wrong domain name")
-	}
-	if q.logLevel <= LOG_LEVEL_INFO {
-		log.Println("quics-protocol: looked up ips ", ips)
-	}
-
-	address := &net.UDPAddr{
-		IP:   ips[0],
-		Port: port,
-	}
-	if q.logLevel <= LOG_LEVEL_INFO {
-		log.Println("quics-protocol: dial to ", address)
-	}
-
-	conn, err := quic.Dial(q.ctx, udpConn, address, tlsConf, q.quicConf)
-	if err != nil {
-		return nil, err
-	}
-
-	newConn, err := connection.New(q.logLevel, conn)
+	newConn, err := q.dial(host, port, tlsConf)
 	if err != nil {
 		return nil, err
 	}
+	q.openControl(newConn)
 
 	go func() {
 		err := q.handler.RouteTransaction(newConn)
@@ -106,6 +104,24 @@ func (q *QP) Dial(host string, port int, tlsConf *tls.Config) (*Connection, erro
 // Return connection instance and error.
 // Need to set receive handler using RecvTransactionHandleFunc before dialing.
 func (q *QP) DialWithTransaction(host string, port int, tlsConf *tls.Config, transactionName string, transactionFunc func(stream *Stream, transactionName string, transactionID []byte) error) (*Connection, error) {
+	newConn, err := q.dial(host, port, tlsConf)
+	if err != nil {
+		return nil, err
+	}
+
+	err = newConn.OpenTransaction(transactionName, transactionFunc)
+	if err != nil {
+		return nil, err
+	}
+
+	go q.handler.RouteTransaction(newConn)
+	q.openControl(newConn)
+	return newConn, nil
+}
+
+// dial connects to the address and exchanges the capabilities with the server.
+// When the server sends a GOAWAY signal, the connection reconnects. (see SetReconnectFunc)
+func (q *QP) dial(host string, port int, tlsConf *tls.Config) (*Connection, error) {
 	if q.logLevel == LOG_LEVEL_DEBUG {
 		q.quicConf.Tracer = qpLog.NewQLogTracer()
 	}
@@ -115,7 +131,8 @@ func (q *QP) DialWithTransaction(host string, port int, tlsConf *tls.Config, tra
 		return nil, err
 	}
 
-	q.ctx, q.cancel = context.WithTimeout(q.ctx, 10*time.Second)
+	ctx, cancel := context.WithTimeout(q.ctx, 10*time.Second)
+	defer cancel()
 
 	ips, err := net.LookupIP(host)
 	if err != nil {
@@ -136,23 +153,84 @@ func (q *QP) DialWithTransaction(host string, port int, tlsConf *tls.Config, tra
 		log.Println("quics-protocol: dial to ", address)
 	}
 
-	conn, err := quic.Dial(q.ctx, udpConn, address, tlsConf, q.quicConf)
+	conn, err := quic.Dial(ctx, udpConn, address, connection.WithALPN(tlsConf), q.quicConf)
 	if err != nil {
 		return nil, err
 	}
 
-	newConn, err := connection.New(q.logLevel, conn)
+	newConn, err := q.newConnection(conn)
 	if err != nil {
 		return nil, err
 	}
+	if newConn.Version() >= 1 {
+		// If the capabilities cannot be exchanged, the connection is kept without the peer capabilities,
+		// so the caller sees why the connection is closed by the peer, like a rejection.
+		err = newConn.Hello()
+		if err != nil {
+			log.Println("quics-protocol: ", err)
+		}
+	}
+	newConn.SetGoAwayFunc(func(goAway connection.GoAway) {
+		q.reconnect(newConn, host, port, tlsConf, goAway)
+	})
+	return newConn, nil
+}
 
-	err = newConn.OpenTransaction(transactionName, transactionFunc)
+// openControl opens the control stream of a connection dialed to a server that supports it.
+// Like the capabilities exchange, the connection is kept if the control stream cannot be opened.
+func (q *QP) openControl(conn *Connection) {
+	if conn.Version() < 1 {
+		return
+	}
+	err := conn.OpenControl()
 	if err != nil {
-		return nil, err
+		log.Println("quics-protocol: ", err)
 	}
+}
 
-	go q.handler.RouteTransaction(newConn)
-	return newConn, nil
+// reconnect reacts to a GOAWAY signal received by a client connection.
+// It dials the address of the signal, or the same address if it is empty, and passes the new connection
+// to the callback of SetReconnectFunc. Then the old connection is closed after its transactions are finished.
+func (q *QP) reconnect(conn *Connection, host string, port int, tlsConf *tls.Config, goAway connection.GoAway) {
+	if q.logLevel <= LOG_LEVEL_INFO {
+		log.Println("quics-protocol: ", "GOAWAY received: ", goAway.Address, goAway.Reason)
+	}
+	q.mutex.Lock()
+	reconnectFunc := q.reconnectFunc
+	q.mutex.Unlock()
+
+	if reconnectFunc != nil {
+		var newConn *Connection
+		var err error
+		if goAway.Address != "" {
+			host, port, err = splitHostPort(goAway.Address)
+		}
+		if err == nil {
+			newConn, err = q.Dial(host, port, tlsConf)
+		}
+		if err != nil {
+			log.Println("quics-protocol: ", err)
+		}
+		reconnectFunc(conn, newConn, err)
+	}
+
+	err := conn.Drain(conn.Conn.Context())
+	if err != nil {
+		return
+	}
+	conn.CloseWithError("going away")
+}
+
+func splitHostPort(address string) (string, int, error) {
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		return "", 0, err
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return "", 0, err
+	}
+	return host, port, nil
 }
 
 // Listen starts a server listening for incoming connections on the UDP address with TLS configuration tlsConf.
@@ -173,7 +251,7 @@ func (q *QP) Listen(address string, tlsConf *tls.Config, connHandler func(conn *
 		return err
 	}
 
-	q.quicListener, err = quic.Listen(udpConn, tlsConf, q.quicConf)
+	q.quicListener, err = quic.Listen(udpConn, q.serverTLSConfig(tlsConf), q.quicConf)
 	if err != nil {
 		return err
 	}
@@ -188,10 +266,20 @@ func (q *QP) Listen(address string, tlsConf *tls.Config, connHandler func(conn *
 			log.Println("quics-protocol: ", "conn accepted")
 		}
 
-		newConn, err := connection.New(q.logLevel, conn)
+		err = q.admission.Admit(conn)
+		if err != nil {
+			if q.logLevel <= LOG_LEVEL_INFO {
+				log.Println("quics-protocol: ", "conn rejected: ", err)
+			}
+			q.getMetrics().Error(qpErr.ConnectionRejectedCode)
+			continue
+		}
+
+		newConn, err := q.newConnection(conn)
 		if err != nil {
 			return err
 		}
+		q.accepted(newConn)
 
 		go q.handler.RouteTransaction(newConn)
 		connHandler(newConn)
@@ -218,7 +306,7 @@ func (q *QP) ListenWithTransaction(address string, tlsConf *tls.Config, transact
 		return err
 	}
 
-	q.quicListener, err = quic.Listen(udpConn, tlsConf, q.quicConf)
+	q.quicListener, err = quic.Listen(udpConn, q.serverTLSConfig(tlsConf), q.quicConf)
 	if err != nil {
 		return err
 	}
@@ -233,45 +321,88 @@ func (q *QP) ListenWithTransaction(address string, tlsConf *tls.Config, transact
 			log.Println("quics-protocol: ", "conn accepted")
 		}
 
-		newConn, err := connection.New(q.logLevel, conn)
+		err = q.admission.Admit(conn)
+		if err != nil {
+			if q.logLevel <= LOG_LEVEL_INFO {
+				log.Println("quics-protocol: ", "conn rejected: ", err)
+			}
+			q.getMetrics().Error(qpErr.ConnectionRejectedCode)
+			continue
+		}
+
+		newConn, err := q.newConnection(conn)
 		if err != nil {
 			newConn.CloseWithError(err.Error())
 			return err
 		}
+		q.accepted(newConn)
 		go func() {
-			stream, err := q.handler.RecvTransaction(newConn)
-			if err != nil {
-				log.Println("quics-protocol: ", err)
-				err := stream.Close()
+			for {
+				stream, err := q.handler.RecvTransaction(newConn)
 				if err != nil {
 					log.Println("quics-protocol: ", err)
-					newConn.CloseWithError(err.Error())
+					err := stream.Close()
+					if err != nil {
+						log.Println("quics-protocol: ", err)
+						newConn.CloseWithError(err.Error())
+					}
+					return
+				}
+
+				transaction, err := newConn.AcceptTransaction(stream)
+				if errors.Is(err, qpErr.ErrHandshakeTimeout) {
+					// The stream is reset already, and the connection is closed after too many timeouts.
+					log.Println("quics-protocol: ", err)
+					continue
+				}
+				if err != nil {
+					log.Println("quics-protocol: ", err)
+					err := stream.Close()
+					if err != nil {
+						log.Println("quics-protocol: ", err)
+						newConn.CloseWithError(err.Error())
+					}
+					return
+				}
+				if q.logLevel <= qpLog.INFO {
+					log.Println("quics-protocol: ", "transaction accepted")
+				}
+				if transaction.TransactionName == connection.HelloTransactionName {
+					// The capabilities are exchanged before the initial transaction.
+					err = newConn.RecvHello(stream)
+					stream.Close()
+					if err != nil {
+						log.Println("quics-protocol: ", err)
+						return
+					}
+					continue
+				}
+				err = newConn.CheckAuthentication(transaction.TransactionName)
+				if err != nil {
+					log.Println("quics-protocol: ", err)
+					m := q.getMetrics()
+					m.TransactionHandled(q.handler.MetricName(transaction.TransactionName), metrics.OutcomeRefused, 0)
+					m.Error(qpErr.UnauthenticatedCode)
+					newConn.Conn.CloseWithError(qpErr.UnauthenticatedCode, err.Error())
+					return
 				}
-				return
-			}
 
-			transaction, err := connection.RecvTransactionHandshake(stream)
-			if err != nil {
-				log.Println("quics-protocol: ", err)
-				err := stream.Close()
+				start := time.Now()
+				err = transactionFunc(newConn, stream, transaction.TransactionName, transaction.TransactionID)
+				outcome := metrics.OutcomeOK
+				if err != nil {
+					outcome = metrics.OutcomeError
+				}
+				q.getMetrics().TransactionHandled(q.handler.MetricName(transaction.TransactionName), outcome, time.Since(start))
 				if err != nil {
 					log.Println("quics-protocol: ", err)
 					newConn.CloseWithError(err.Error())
+					return
 				}
-				return
-			}
-			if q.logLevel <= qpLog.INFO {
-				log.Println("quics-protocol: ", "transaction accepted")
-			}
 
-			err = transactionFunc(newConn, stream, transaction.TransactionName, transaction.TransactionID)
-			if err != nil {
-				log.Println("quics-protocol: ", err)
-				newConn.CloseWithError(err.Error())
+				go q.handler.RouteTransaction(newConn)
 				return
 			}
-
-			go q.handler.RouteTransaction(newConn)
 		}()
 	}
 }
@@ -319,3 +450,220 @@ func (q *QP) DefaultRecvTransactionHandleFunc(callback func(conn *Connection, st
 func (q *QP) GetErrChan() chan error {
 	return q.handler.GetErrChan()
 }
+
+// SetUploadLimit limits the rate of sending data of all connections of the quics-protocol instance in bytes per second.
+// The limit can be changed at any time. A limit of 0 or less means unlimited.
+// Each connection and transaction can have its own limit too. (see Connection.SetUploadLimit and Stream.SetUploadLimit)
+func (q *QP) SetUploadLimit(bytesPerSecond int64) {
+	q.uploadLimiter.SetLimit(bytesPerSecond)
+}
+
+// SetDownloadLimit limits the rate of receiving data of all connections of the quics-protocol instance in bytes per second.
+// The limit can be changed at any time. A limit of 0 or less means unlimited.
+// Each connection and transaction can have its own limit too. (see Connection.SetDownloadLimit and Stream.SetDownloadLimit)
+func (q *QP) SetDownloadLimit(bytesPerSecond int64) {
+	q.downloadLimiter.SetLimit(bytesPerSecond)
+}
+
+// SetLimits sets the limits on the data received from peers.
+// The limits are applied to the connections established after it is called. (DefaultLimits by default)
+// Each connection and transaction can have its own limits too. (see Connection.SetLimits and Stream.SetLimits)
+func (q *QP) SetLimits(limits Limits) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.limits = limits
+}
+
+// SetTimeouts sets the timeouts against peers that open transactions and stay silent.
+// The timeouts are applied to the connections established after it is called. (DefaultTimeouts by default)
+// Each connection can have its own timeouts too. (see Connection.SetTimeouts and Stream.SetIdleTimeout)
+func (q *QP) SetTimeouts(timeouts Timeouts) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.timeouts = timeouts
+}
+
+// SetConcurrency sets the limits of the transactions handled at the same time.
+// The limits are applied to the connections established after it is called. (unlimited by default)
+// When the queue of the transactions waiting to be handled is full, no more transactions are accepted,
+// so QUIC flow control makes the peers wait before opening more transactions.
+func (q *QP) SetConcurrency(concurrency Concurrency) {
+	q.handler.SetConcurrency(concurrency)
+}
+
+// SetAdmission sets the rules to accept or reject the connections accepted by Listen and ListenWithTransaction.
+// The rules are checked before any transaction of the connection is handled,
+// and rejected connections are closed with ConnectionRejectedCode. (all connections are accepted by default)
+func (q *QP) SetAdmission(admission Admission) {
+	q.admission.SetAdmission(admission)
+}
+
+// SetAuthenticators makes the server require the authentication phase with one of the authenticators
+// right after a connection is established. Until a client is authenticated, its transactions are refused with UnauthenticatedCode.
+// The authenticators are applied to the connections established after it is called. (no authentication by default)
+// The client authenticates with Connection.Authenticate, and the authenticated principal is returned by Connection.Principal.
+// Because the initial transaction of ListenWithTransaction comes before the authentication phase,
+// ListenWithTransaction closes the connections requiring authentication instead of handling their initial transaction.
+func (q *QP) SetAuthenticators(authenticators ...Authenticator) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.authenticators = authenticators
+}
+
+// SetEnrollment makes the server issue client certificates to the clients that request them with Connection.Enroll.
+// The enrollment is applied to the connections established after it is called. (no enrollment by default)
+// To let new clients without a certificate connect, set ClientAuth of the TLS configuration to tls.VerifyClientCertIfGiven
+// instead of using SetClientCAs, and check Connection.PeerIdentity in the handlers.
+func (q *QP) SetEnrollment(enrollment Enrollment) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.enrollment = &enrollment
+}
+
+// SetCapabilities sets the capabilities sent to the peers when a connection starts. (DefaultCapabilities by default)
+// The version, maximum message size and datagram support are filled from each connection.
+// The capabilities are applied to the connections established after it is called.
+func (q *QP) SetCapabilities(capabilities Capabilities) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.capabilities = capabilities
+}
+
+// SetReconnectFunc sets the callback function called when a client connection reconnects after a GOAWAY signal of the server.
+// The old connection stops opening transactions and is closed after its transactions are finished,
+// and newConn is the connection dialed to the address of the signal, or nil with err if it cannot be dialed.
+// Without the callback, the old connection is closed after its transactions are finished without reconnecting.
+func (q *QP) SetReconnectFunc(reconnectFunc func(oldConn *Connection, newConn *Connection, err error)) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.reconnectFunc = reconnectFunc
+}
+
+// GoAway sends a GOAWAY signal to all the connections of the quics-protocol instance for a graceful migration.
+// The clients stop opening transactions, finish the open ones and reconnect to the address, or the same address if it is empty.
+// The connections without a control stream, like those of older clients, are skipped. The first error is returned.
+func (q *QP) GoAway(address string, reason string) error {
+	q.mutex.Lock()
+	conns := make([]*Connection, 0, len(q.conns))
+	for conn := range q.conns {
+		conns = append(conns, conn)
+	}
+	q.mutex.Unlock()
+
+	var firstErr error
+	for _, conn := range conns {
+		err := conn.GoAway(address, reason)
+		if err != nil && err != qpErr.ErrNoControlStream && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// SetPingInterval makes the connections ping their peers every interval on the control stream,
+// so the round-trip times of Connection.Stats are kept up to date. (no pings by default)
+// It is applied to the connections established after it is called.
+func (q *QP) SetPingInterval(interval time.Duration) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.pingInterval = interval
+}
+
+// Stats returns the statistics of all the open connections of the quics-protocol instance.
+// The statistics of each connection are returned by Connection.Stats.
+func (q *QP) Stats() AggregateStats {
+	q.mutex.Lock()
+	conns := make([]*Connection, 0, len(q.conns))
+	for conn := range q.conns {
+		conns = append(conns, conn)
+	}
+	q.mutex.Unlock()
+
+	stats := make([]ConnectionStats, 0, len(conns))
+	for _, conn := range conns {
+		stats = append(stats, conn.Stats())
+	}
+	return connection.Aggregate(stats)
+}
+
+// SetMetrics sets the metrics that the connections, transactions and streams report to. (Noop by default)
+// The connections accepted, the transactions handled and their handler latency, the bytes by request type
+// and the errors by code are reported. It is applied to the connections established after it is called.
+// NewPrometheus creates the metrics exposed in the Prometheus text exposition format.
+func (q *QP) SetMetrics(m Metrics) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.metrics = m
+	q.handler.SetMetrics(m)
+}
+
+func (q *QP) getMetrics() Metrics {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return q.metrics
+}
+
+// accepted reports a connection accepted by the server to the metrics, and reports it again when it is closed.
+func (q *QP) accepted(conn *Connection) {
+	m := conn.Metrics()
+	m.ConnectionAccepted()
+	go func() {
+		<-conn.Conn.Context().Done()
+		m.ConnectionClosed()
+	}()
+}
+
+// SetClientCAs makes the server require a client certificate signed by one of the CAs in the pool.
+// It is applied to Listen and ListenWithTransaction called after it is called. (client certificates are not required by default)
+// The identity of the verified certificate is returned by Connection.PeerIdentity.
+// To reject revoked certificates, set Admission.Revocation. (see SetAdmission)
+func (q *QP) SetClientCAs(clientCAs *x509.CertPool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.clientCAs = clientCAs
+}
+
+// serverTLSConfig returns the TLS configuration of the server with the ALPN identifier of the protocol version
+// and the client CAs of the quics-protocol instance.
+func (q *QP) serverTLSConfig(tlsConf *tls.Config) *tls.Config {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	tlsConf = connection.WithALPN(tlsConf)
+	if q.clientCAs == nil {
+		return tlsConf
+	}
+	tlsConf.ClientCAs = q.clientCAs
+	tlsConf.ClientAuth = tls.RequireAndVerifyClientCert
+	return tlsConf
+}
+
+// newConnection creates a new connection instance with the limits and timeouts of the quics-protocol instance.
+// The connection is tracked by the quics-protocol instance until it is closed.
+func (q *QP) newConnection(conn quic.Connection) (*Connection, error) {
+	newConn, err := connection.New(q.logLevel, conn)
+	if err != nil {
+		return nil, err
+	}
+	newConn.SetParentLimiters(q.uploadLimiter, q.downloadLimiter)
+	q.mutex.Lock()
+	newConn.SetLimits(q.limits)
+	newConn.SetTimeouts(q.timeouts)
+	newConn.SetAuthenticators(q.authenticators)
+	newConn.SetEnrollment(q.enrollment)
+	newConn.SetCapabilities(q.capabilities)
+	newConn.SetMetrics(q.metrics)
+	q.conns[newConn] = struct{}{}
+	pingInterval := q.pingInterval
+	q.mutex.Unlock()
+	if pingInterval > 0 {
+		newConn.KeepPinging(pingInterval)
+	}
+
+	go func() {
+		<-conn.Context().Done()
+		q.mutex.Lock()
+		delete(q.conns, newConn)
+		q.mutex.Unlock()
+	}()
+	return newConn, nil
+}
@@ -0,0 +1,79 @@
+package main_test
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	qp "github.com/quic-s/quics-protocol"
+)
+
+func TestAdmission(t *testing.T) {
+	server, err := qp.New(qp.LOG_LEVEL_ERROR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverNames := make(chan string, 3)
+	server.SetAdmission(qp.Admission{
+		MaxConnectionsPerIP: 1,
+		AcceptConnection: func(info *qp.ConnectionInfo) error {
+			serverNames <- info.ServerName
+			if info.ServerName == "rejected.example" {
+				return errors.New("unknown server name")
+			}
+			return nil
+		},
+	})
+	cert, err := qp.GetCertificate("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Listen(":18084", &tls.Config{Certificates: cert, NextProtos: []string{"quics-protocol"}}, func(conn *qp.Connection) {})
+	defer server.Close()
+	time.Sleep(200 * time.Millisecond)
+
+	dial := func(serverName string) *qp.Connection {
+		client, err := qp.New(qp.LOG_LEVEL_ERROR)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn, err := client.Dial("localhost", 18084, &tls.Config{InsecureSkipVerify: true, ServerName: serverName, NextProtos: []string{"quics-protocol"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return conn
+	}
+	rejected := func(conn *qp.Connection) bool {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		_, err := conn.Conn.AcceptStream(ctx)
+		appErr := &quic.ApplicationError{}
+		return errors.As(err, &appErr) && appErr.ErrorCode == qp.ConnectionRejectedCode
+	}
+
+	first := dial("accepted.example")
+	if rejected(first) {
+		t.Fatal("first connection is rejected")
+	}
+	if name := <-serverNames; name != "accepted.example" {
+		t.Fatalf("server name %q is passed to the hook", name)
+	}
+
+	second := dial("accepted.example")
+	if !rejected(second) {
+		t.Fatal("connection over the limit per IP is not rejected")
+	}
+
+	first.Close()
+	time.Sleep(200 * time.Millisecond)
+	third := dial("rejected.example")
+	if !rejected(third) {
+		t.Fatal("connection rejected by the hook is not closed")
+	}
+	if name := <-serverNames; name != "rejected.example" {
+		t.Fatalf("server name %q is passed to the hook", name)
+	}
+}
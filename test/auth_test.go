@@ -0,0 +1,211 @@
+package main_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	qp "github.com/quic-s/quics-protocol"
+	"github.com/quic-s/quics-protocol/pkg/auth"
+)
+
+func TestAuthentication(t *testing.T) {
+	key := []byte("pre-shared key of alice")
+	server, err := qp.New(qp.LOG_LEVEL_ERROR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.SetAuthenticators(
+		qp.NewHMACAuthenticator(map[string][]byte{"alice": key}),
+		qp.NewTokenAuthenticator(map[string]string{"token of bob": "bob"}),
+	)
+	principals := make(chan string, 1)
+	err = server.RecvTransactionHandleFunc("whoami", func(conn *qp.Connection, stream *qp.Stream, transactionName string, transactionID []byte) error {
+		principal, _ := conn.Principal()
+		principals <- principal
+		return stream.SendBMessage([]byte(principal))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := qp.GetCertificate("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Listen(":18086", &tls.Config{Certificates: cert, NextProtos: []string{"quics-protocol"}}, func(conn *qp.Connection) {})
+	defer server.Close()
+	time.Sleep(200 * time.Millisecond)
+
+	dial := func() *qp.Connection {
+		client, err := qp.New(qp.LOG_LEVEL_ERROR)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn, err := client.Dial("localhost", 18086, &tls.Config{InsecureSkipVerify: true, NextProtos: []string{"quics-protocol"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return conn
+	}
+	whoami := func(stream *qp.Stream, transactionName string, transactionID []byte) error {
+		_, err := stream.RecvBMessage()
+		return err
+	}
+
+	conn := dial()
+	defer conn.Close()
+	err = conn.OpenTransaction("whoami", whoami)
+	streamErr := &quic.StreamError{}
+	if !errors.As(err, &streamErr) || streamErr.ErrorCode != qp.UnauthenticatedCode {
+		t.Fatalf("transaction before authentication is not refused: %v", err)
+	}
+
+	err = conn.Authenticate(qp.NewHMACCredential("alice", key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if principal, ok := conn.Principal(); !ok || principal != "alice" {
+		t.Fatalf("principal alice is expected, but got %q", principal)
+	}
+	err = conn.OpenTransaction("whoami", whoami)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if principal := <-principals; principal != "alice" {
+		t.Fatalf("principal alice is expected on the server, but got %q", principal)
+	}
+
+	conn = dial()
+	defer conn.Close()
+	err = conn.Authenticate(qp.NewTokenCredential("wrong token"))
+	if err == nil {
+		t.Fatal("authentication with a wrong token succeeded")
+	}
+	select {
+	case <-conn.Conn.Context().Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("connection is not closed after the authentication failed")
+	}
+}
+
+func TestCallbackAuthenticatorBinding(t *testing.T) {
+	key := []byte("one-time key of carol")
+	server, err := qp.New(qp.LOG_LEVEL_ERROR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.SetAuthenticators(qp.NewCallbackAuthenticator("otp", func(identity string, challenge []byte, response []byte, binding []byte) (string, error) {
+		if identity != "carol" || !bytes.Equal(response, auth.Sign(key, challenge, binding)) {
+			return "", errors.New("wrong response")
+		}
+		return "principal of carol", nil
+	}))
+	cert, err := qp.GetCertificate("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Listen(":18107", &tls.Config{Certificates: cert, NextProtos: []string{"quics-protocol"}}, func(conn *qp.Connection) {})
+	defer server.Close()
+	time.Sleep(200 * time.Millisecond)
+
+	dial := func() *qp.Connection {
+		client, err := qp.New(qp.LOG_LEVEL_ERROR)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn, err := client.Dial("localhost", 18107, &tls.Config{InsecureSkipVerify: true, NextProtos: []string{"quics-protocol"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return conn
+	}
+
+	// The binding of the first connection is kept to be replayed on the second connection.
+	var firstBinding []byte
+	first := dial()
+	defer first.Close()
+	err = first.Authenticate(qp.NewCallbackCredential("otp", "carol", func(challenge []byte, binding []byte) ([]byte, error) {
+		firstBinding = binding
+		return auth.Sign(key, challenge, binding), nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if principal, ok := first.Principal(); !ok || principal != "principal of carol" {
+		t.Fatalf("principal of carol is expected, but got %q", principal)
+	}
+
+	// A response made for the TLS session of another connection is rejected.
+	second := dial()
+	defer second.Close()
+	err = second.Authenticate(qp.NewCallbackCredential("otp", "carol", func(challenge []byte, binding []byte) ([]byte, error) {
+		if bytes.Equal(binding, firstBinding) {
+			return nil, errors.New("binding is the same on both connections")
+		}
+		return auth.Sign(key, challenge, firstBinding), nil
+	}))
+	if err == nil {
+		t.Fatal("response replayed from another connection is accepted")
+	}
+	select {
+	case <-second.Conn.Context().Done():
+		appErr := &quic.ApplicationError{}
+		if !errors.As(context.Cause(second.Conn.Context()), &appErr) || appErr.ErrorCode != qp.AuthenticationFailedCode {
+			t.Fatalf("connection closed with authentication failed code is expected: %v", context.Cause(second.Conn.Context()))
+		}
+	case <-time.After(time.Second):
+		t.Fatal("connection is not closed after the authentication failed")
+	}
+}
+
+func TestPreAuthLimit(t *testing.T) {
+	server, err := qp.New(qp.LOG_LEVEL_ERROR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.SetAuthenticators(qp.NewTokenAuthenticator(map[string]string{"token": "principal"}))
+	server.SetTimeouts(qp.Timeouts{HandshakeTimeout: 500 * time.Millisecond})
+	cert, err := qp.GetCertificate("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Listen(":18108", &tls.Config{Certificates: cert, NextProtos: []string{"quics-protocol"}}, func(conn *qp.Connection) {})
+	defer server.Close()
+	time.Sleep(200 * time.Millisecond)
+
+	client, err := qp.New(qp.LOG_LEVEL_ERROR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := client.Dial("localhost", 18108, &tls.Config{InsecureSkipVerify: true, NextProtos: []string{"quics-protocol"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// Open the authentication phases and stay silent.
+	silent := func(stream *qp.Stream, transactionName string, transactionID []byte) error {
+		_, err := stream.RecvBMessage()
+		return err
+	}
+	for i := 0; i < qp.MaxPreAuthTransactions; i++ {
+		go conn.OpenTransaction("quics-protocol:auth", silent)
+	}
+	time.Sleep(200 * time.Millisecond)
+	err = conn.OpenTransaction("quics-protocol:auth", silent)
+	streamErr := &quic.StreamError{}
+	if !errors.As(err, &streamErr) || streamErr.ErrorCode != qp.LimitExceededCode {
+		t.Fatalf("stream reset with limit exceeded code is expected: %v", err)
+	}
+
+	// The silent authentication phases time out, so the connection is closed.
+	select {
+	case <-conn.Conn.Context().Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("connection is not closed after the authentication phases timed out")
+	}
+}
@@ -0,0 +1,66 @@
+package main_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	qp "github.com/quic-s/quics-protocol"
+)
+
+func TestLocalCA(t *testing.T) {
+	root, err := qp.NewRootCA(qp.CertificateOptions{CommonName: "root", Validity: 24 * time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	intermediate, err := root.NewIntermediate(qp.CertificateOptions{CommonName: "intermediate", Validity: 24 * time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	err = intermediate.Write(filepath.Join(dir, "ca.key"), filepath.Join(dir, "ca.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := qp.LoadCA(filepath.Join(dir, "ca.key"), filepath.Join(dir, "ca.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serverCert, err := ca.IssueServer(qp.CertificateOptions{CommonName: "server", Hosts: []string{"localhost"}, Validity: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientCert, err := ca.IssueClient(qp.CertificateOptions{KeyType: qp.KeyEd25519, CommonName: "client", Validity: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server, err := qp.New(qp.LOG_LEVEL_ERROR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	identities := make(chan *qp.Identity, 1)
+	go server.Listen(":18087", ca.ServerTLSConfig(serverCert), func(conn *qp.Connection) {
+		identities <- conn.PeerIdentity()
+	})
+	defer server.Close()
+	time.Sleep(200 * time.Millisecond)
+
+	client, err := qp.New(qp.LOG_LEVEL_ERROR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := client.Dial("localhost", 18087, root.ClientTLSConfig(clientCert, "localhost"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	identity := <-identities
+	if identity == nil || identity.Subject.CommonName != "client" {
+		t.Fatalf("wrong peer identity: %+v", identity)
+	}
+	if peer := conn.PeerIdentity(); peer == nil || peer.Subject.CommonName != "server" {
+		t.Fatalf("wrong server identity: %+v", peer)
+	}
+}
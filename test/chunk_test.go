@@ -0,0 +1,181 @@
+package main_test
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	qp "github.com/quic-s/quics-protocol"
+	"github.com/quic-s/quics-protocol/pkg/chunk"
+	qpStream "github.com/quic-s/quics-protocol/pkg/stream"
+	"github.com/quic-s/quics-protocol/pkg/types/fileinfo"
+	pb "github.com/quic-s/quics-protocol/proto/v1"
+)
+
+func TestChunkDeduplication(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	data := make([]byte, 2<<20)
+	rnd.Read(data)
+	shifted := append([]byte("shifted by some bytes"), data...)
+
+	store, err := chunk.NewDirStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	put := func(content []byte) ([]chunk.Hash, int) {
+		hashes := []chunk.Hash{}
+		stored := 0
+		chunker := chunk.NewChunker(bytes.NewReader(content))
+		for {
+			ref, chunkData, err := chunker.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			hashes = append(hashes, ref.Hash)
+			has, err := store.Has(ref.Hash)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !has {
+				stored++
+				err = store.Put(ref.Hash, chunkData)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+		return hashes, stored
+	}
+
+	_, _ = put(data)
+	hashes, stored := put(shifted)
+	if stored > 2 {
+		t.Fatalf("too many new chunks for shifted content: %d of %d", stored, len(hashes))
+	}
+
+	rebuilt, err := io.ReadAll(chunk.NewReader(store, hashes))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(rebuilt, shifted) {
+		t.Fatal("rebuilt file is not equal to the original file")
+	}
+}
+
+// countingStore counts the chunks put in the store.
+type countingStore struct {
+	chunk.Store
+	puts int
+}
+
+func (c *countingStore) Put(hash chunk.Hash, data []byte) error {
+	c.puts++
+	return c.Store.Put(hash, data)
+}
+
+func TestSendFileChunked(t *testing.T) {
+	rnd := rand.New(rand.NewSource(3))
+	data := make([]byte, 2<<20)
+	rnd.Read(data)
+	dir := t.TempDir()
+	srcPath := filepath.Join(dir, "src")
+	err := os.WriteFile(srcPath, data, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dirStore, err := chunk.NewDirStore(filepath.Join(dir, "store"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := &countingStore{Store: dirStore}
+
+	recv := func(port int, dstPath string) {
+		serverErr, clientErr := transact(t, port, func(conn *qp.Connection, stream *qp.Stream) error {
+			_, err := stream.RecvFileChunked(store, dstPath)
+			return err
+		}, func(conn *qp.Connection, stream *qp.Stream) error {
+			return stream.SendFileChunked(srcPath)
+		})
+		if serverErr != nil || clientErr != nil {
+			t.Fatal(serverErr, clientErr)
+		}
+		received, err := os.ReadFile(dstPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(received, data) {
+			t.Fatal("received file is not equal to the sent file")
+		}
+	}
+
+	recv(18112, filepath.Join(dir, "first"))
+	if store.puts == 0 {
+		t.Fatal("no chunk is received for a new file")
+	}
+	// All chunks are in the store already, so the second transfer requests no chunk.
+	store.puts = 0
+	recv(18113, filepath.Join(dir, "second"))
+	if store.puts != 0 {
+		t.Fatalf("%d chunks are received for a file in the store", store.puts)
+	}
+}
+
+func TestRecvFileChunkedSizeMismatch(t *testing.T) {
+	dir := t.TempDir()
+	srcPath := filepath.Join(dir, "src")
+	dstPath := filepath.Join(dir, "dst")
+	data := []byte("content of the file")
+	err := os.WriteFile(srcPath, data, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := chunk.NewDirStore(filepath.Join(dir, "store"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The manifest has more bytes than the size in the metadata of the file.
+	serverErr, _ := transact(t, 18114, func(conn *qp.Connection, stream *qp.Stream) error {
+		_, err := stream.RecvFileChunked(store, dstPath)
+		return err
+	}, func(conn *qp.Connection, stream *qp.Stream) error {
+		osFileInfo, err := os.Stat(srcPath)
+		if err != nil {
+			return err
+		}
+		fileInfo, err := fileinfo.NewFromOSFileInfo(osFileInfo)
+		if err != nil {
+			return err
+		}
+		requestId, err := uuid.New().MarshalBinary()
+		if err != nil {
+			return err
+		}
+		err = qpStream.WriteHeader(stream, pb.RequestType_FILE_CHUNKED, requestId, "")
+		if err != nil {
+			return err
+		}
+		err = qpStream.WriteFileInfo(stream, fileInfo)
+		if err != nil {
+			return err
+		}
+		return qpStream.WriteChunkManifest(stream, []chunk.Ref{
+			{Hash: chunk.Sum(data), Size: int64(len(data))},
+			{Hash: chunk.Sum(data), Size: int64(len(data))},
+		})
+	})
+	if serverErr == nil {
+		t.Fatal("chunks larger than the file are accepted")
+	}
+	if _, err := os.Stat(dstPath); !os.IsNotExist(err) {
+		t.Fatalf("file with a wrong manifest is written: %v", err)
+	}
+}
@@ -0,0 +1,175 @@
+package main_test
+
+import (
+	"crypto/rand"
+	"crypto/tls"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	qp "github.com/quic-s/quics-protocol"
+)
+
+func TestConcurrencyLimit(t *testing.T) {
+	server, err := qp.New(qp.LOG_LEVEL_ERROR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.SetConcurrency(qp.Concurrency{
+		MaxTransactions: 2,
+		MaxQueue:        1,
+	})
+	running, maxRunning := int32(0), int32(0)
+	err = server.RecvTransactionHandleFunc("work", func(conn *qp.Connection, stream *qp.Stream, transactionName string, transactionID []byte) error {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			max := atomic.LoadInt32(&maxRunning)
+			if n <= max || atomic.CompareAndSwapInt32(&maxRunning, max, n) {
+				break
+			}
+		}
+		time.Sleep(100 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		return stream.SendBMessage([]byte("done"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := qp.GetCertificate("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Listen(":18083", &tls.Config{Certificates: cert, NextProtos: []string{"quics-protocol"}}, func(conn *qp.Connection) {})
+	defer server.Close()
+	time.Sleep(200 * time.Millisecond)
+
+	client, err := qp.New(qp.LOG_LEVEL_ERROR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := client.Dial("localhost", 18083, &tls.Config{InsecureSkipVerify: true, NextProtos: []string{"quics-protocol"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := conn.OpenTransaction("work", func(stream *qp.Stream, transactionName string, transactionID []byte) error {
+				_, err := stream.RecvBMessage()
+				return err
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if maxRunning != 2 {
+		t.Fatalf("%d transactions are handled at the same time", maxRunning)
+	}
+}
+
+func TestUnknownReservedTransaction(t *testing.T) {
+	handled := false
+	_, clientErr := transact(t, 18104, func(conn *qp.Connection, stream *qp.Stream) error {
+		return nil
+	}, func(conn *qp.Connection, stream *qp.Stream) error {
+		return conn.OpenTransaction("quics-protocol:unknown", func(stream *qp.Stream, transactionName string, transactionID []byte) error {
+			_, err := stream.RecvBMessage()
+			handled = err == nil
+			return err
+		})
+	})
+	streamErr := &quic.StreamError{}
+	if handled || !errors.As(clientErr, &streamErr) || streamErr.ErrorCode != qp.UnknownTransactionCode {
+		t.Fatalf("stream reset with unknown transaction code is expected: %v", clientErr)
+	}
+}
+
+func TestConcurrencyParallelFile(t *testing.T) {
+	server, err := qp.New(qp.LOG_LEVEL_ERROR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The transaction receiving the file holds the only running slot,
+	// and the queue is filled by the transactions waiting for it.
+	server.SetConcurrency(qp.Concurrency{
+		MaxTransactions: 1,
+		MaxQueue:        2,
+	})
+	dir := t.TempDir()
+	srcPath := filepath.Join(dir, "src")
+	data := make([]byte, 6<<20)
+	rand.Read(data)
+	err = os.WriteFile(srcPath, data, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = server.RecvTransactionHandleFunc("parallel", func(conn *qp.Connection, stream *qp.Stream, transactionName string, transactionID []byte) error {
+		_, err := conn.RecvFileParallel(stream, filepath.Join(dir, "dst"))
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = server.RecvTransactionHandleFunc("work", func(conn *qp.Connection, stream *qp.Stream, transactionName string, transactionID []byte) error {
+		return stream.SendBMessage([]byte("done"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := qp.GetCertificate("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Listen(":18105", &tls.Config{Certificates: cert, NextProtos: []string{"quics-protocol"}}, func(conn *qp.Connection) {})
+	defer server.Close()
+	time.Sleep(200 * time.Millisecond)
+
+	client, err := qp.New(qp.LOG_LEVEL_ERROR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := client.Dial("localhost", 18105, &tls.Config{InsecureSkipVerify: true, NextProtos: []string{"quics-protocol"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	done := make(chan error, 4)
+	go func() {
+		done <- conn.OpenTransaction("parallel", func(stream *qp.Stream, transactionName string, transactionID []byte) error {
+			// Wait until the queue is filled before sending the ranges.
+			time.Sleep(300 * time.Millisecond)
+			return conn.SendFileParallel(stream, srcPath, 2)
+		})
+	}()
+	time.Sleep(100 * time.Millisecond)
+	for i := 0; i < 3; i++ {
+		go func() {
+			done <- conn.OpenTransaction("work", func(stream *qp.Stream, transactionName string, transactionID []byte) error {
+				_, err := stream.RecvBMessage()
+				return err
+			})
+		}()
+	}
+	for i := 0; i < 4; i++ {
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatal(err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("transactions are deadlocked")
+		}
+	}
+}
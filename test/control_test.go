@@ -0,0 +1,288 @@
+package main_test
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	qp "github.com/quic-s/quics-protocol"
+)
+
+func TestControl(t *testing.T) {
+	cert, err := qp.GetCertificate("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	listen := func(address string) (*qp.QP, chan *qp.Connection) {
+		server, err := qp.New(qp.LOG_LEVEL_ERROR)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conns := make(chan *qp.Connection, 1)
+		go server.Listen(address, &tls.Config{Certificates: cert, NextProtos: []string{"quics-protocol"}}, func(conn *qp.Connection) {
+			conns <- conn
+		})
+		return server, conns
+	}
+	oldServer, oldConns := listen(":18092")
+	defer oldServer.Close()
+	newServer, newConns := listen(":18093")
+	defer newServer.Close()
+	time.Sleep(200 * time.Millisecond)
+
+	client, err := qp.New(qp.LOG_LEVEL_ERROR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reconnected := make(chan *qp.Connection, 1)
+	client.SetReconnectFunc(func(oldConn *qp.Connection, newConn *qp.Connection, err error) {
+		if err != nil {
+			t.Error(err)
+		}
+		reconnected <- newConn
+	})
+	conn, err := client.Dial("localhost", 18092, &tls.Config{InsecureSkipVerify: true, NextProtos: []string{"quics-protocol"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	serverConn := <-oldConns
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	rtt, err := conn.Ping(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rtt <= 0 || conn.RTT() != rtt {
+		t.Fatalf("wrong round-trip time %v, %v", rtt, conn.RTT())
+	}
+	// The server pings the client on the same control stream.
+	_, err = serverConn.Ping(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	settings := make(chan map[string]string, 1)
+	serverConn.SetSettingsFunc(func(update map[string]string) {
+		settings <- update
+	})
+	err = conn.UpdateSettings(map[string]string{"chunk-size": "65536"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case update := <-settings:
+		if update["chunk-size"] != "65536" || serverConn.PeerSettings()["chunk-size"] != "65536" {
+			t.Fatalf("wrong settings %v", update)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("settings are not received")
+	}
+
+	err = oldServer.GoAway("localhost:18093", "maintenance")
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case newConn := <-reconnected:
+		defer newConn.Close()
+		if newConn.Conn.RemoteAddr().String() == conn.Conn.RemoteAddr().String() {
+			t.Fatal("client reconnected to the same address")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("client did not reconnect after GOAWAY")
+	}
+	<-newConns
+
+	goAway, ok := conn.GoingAway()
+	if !ok || goAway.Address != "localhost:18093" || goAway.Reason != "maintenance" {
+		t.Fatalf("wrong GOAWAY %+v", goAway)
+	}
+	err = conn.OpenTransaction("test", func(stream *qp.Stream, transactionName string, transactionID []byte) error {
+		return nil
+	})
+	if !errors.Is(err, qp.ErrGoingAway) {
+		t.Fatalf("transaction after GOAWAY is not refused: %v", err)
+	}
+	select {
+	case <-conn.Conn.Context().Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("old connection is not closed after GOAWAY")
+	}
+}
+
+func TestControlAuthenticated(t *testing.T) {
+	server, err := qp.New(qp.LOG_LEVEL_ERROR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.SetAuthenticators(qp.NewTokenAuthenticator(map[string]string{"token of bob": "bob"}))
+	cert, err := qp.GetCertificate("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conns := make(chan *qp.Connection, 1)
+	go server.Listen(":18110", &tls.Config{Certificates: cert, NextProtos: []string{"quics-protocol"}}, func(conn *qp.Connection) {
+		conns <- conn
+	})
+	defer server.Close()
+	time.Sleep(200 * time.Millisecond)
+
+	client, err := qp.New(qp.LOG_LEVEL_ERROR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := client.Dial("localhost", 18110, &tls.Config{InsecureSkipVerify: true, NextProtos: []string{"quics-protocol"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	serverConn := <-conns
+	err = conn.Authenticate(qp.NewTokenCredential("token of bob"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The control stream is opened before the authentication, and it is kept on the authenticated connection.
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	_, err = conn.Ping(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = serverConn.Ping(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = server.GoAway("", "maintenance")
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-conn.Conn.Context().Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("connection is not closed after GOAWAY")
+	}
+	goAway, ok := conn.GoingAway()
+	if !ok || goAway.Reason != "maintenance" {
+		t.Fatalf("GOAWAY of the server is not received: %+v", goAway)
+	}
+}
+
+func TestControlBeforeAuthentication(t *testing.T) {
+	server, err := qp.New(qp.LOG_LEVEL_ERROR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.SetAuthenticators(qp.NewTokenAuthenticator(map[string]string{"token of bob": "bob"}))
+	cert, err := qp.GetCertificate("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conns := make(chan *qp.Connection, 1)
+	go server.Listen(":18117", &tls.Config{Certificates: cert, NextProtos: []string{"quics-protocol"}}, func(conn *qp.Connection) {
+		conns <- conn
+	})
+	defer server.Close()
+	time.Sleep(200 * time.Millisecond)
+
+	client, err := qp.New(qp.LOG_LEVEL_ERROR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := client.Dial("localhost", 18117, &tls.Config{InsecureSkipVerify: true, NextProtos: []string{"quics-protocol"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	serverConn := <-conns
+	settings := make(chan map[string]string, 1)
+	serverConn.SetSettingsFunc(func(update map[string]string) {
+		settings <- update
+	})
+
+	// Pings are answered before the authentication, but settings are refused.
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	_, err = conn.Ping(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = conn.UpdateSettings(map[string]string{"chunk-size": "65536"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	streamErr := &quic.StreamError{}
+	for {
+		pingCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+		_, err = conn.Ping(pingCtx)
+		cancel()
+		if errors.As(err, &streamErr) || ctx.Err() != nil {
+			break
+		}
+	}
+	if !errors.As(err, &streamErr) || streamErr.ErrorCode != qp.UnauthenticatedCode {
+		t.Fatalf("control stream reset with unauthenticated code is expected: %v", err)
+	}
+	select {
+	case update := <-settings:
+		t.Fatalf("settings of an unauthenticated peer are accepted: %v", update)
+	default:
+	}
+	if len(serverConn.PeerSettings()) != 0 {
+		t.Fatalf("settings of an unauthenticated peer are kept: %v", serverConn.PeerSettings())
+	}
+}
+
+func TestControlSettingsLimit(t *testing.T) {
+	cert, err := qp.GetCertificate("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := qp.New(qp.LOG_LEVEL_ERROR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Listen(":18118", &tls.Config{Certificates: cert, NextProtos: []string{"quics-protocol"}}, func(conn *qp.Connection) {})
+	defer server.Close()
+	time.Sleep(200 * time.Millisecond)
+
+	client, err := qp.New(qp.LOG_LEVEL_ERROR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := client.Dial("localhost", 18118, &tls.Config{InsecureSkipVerify: true, NextProtos: []string{"quics-protocol"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	settings := make(map[string]string)
+	for i := 0; i <= qp.MaxSettings; i++ {
+		settings["key-"+strconv.Itoa(i)] = "value"
+	}
+	err = conn.UpdateSettings(settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	streamErr := &quic.StreamError{}
+	for {
+		pingCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+		_, err = conn.Ping(pingCtx)
+		cancel()
+		if errors.As(err, &streamErr) || ctx.Err() != nil {
+			break
+		}
+	}
+	if !errors.As(err, &streamErr) || streamErr.ErrorCode != qp.LimitExceededCode {
+		t.Fatalf("control stream reset with limit exceeded code is expected: %v", err)
+	}
+}
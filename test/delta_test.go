@@ -0,0 +1,162 @@
+package main_test
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	qp "github.com/quic-s/quics-protocol"
+	"github.com/quic-s/quics-protocol/pkg/delta"
+	qpStream "github.com/quic-s/quics-protocol/pkg/stream"
+	"github.com/quic-s/quics-protocol/pkg/types/fileinfo"
+	pb "github.com/quic-s/quics-protocol/proto/v1"
+)
+
+func TestDeltaRoundTrip(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	old := make([]byte, 1<<20+123)
+	rnd.Read(old)
+
+	// change a few regions, insert and delete some bytes
+	updated := append([]byte{}, old[:10000]...)
+	updated = append(updated, []byte("inserted data")...)
+	updated = append(updated, old[10000:500000]...)
+	updated = append(updated, old[500100:]...)
+	copy(updated[800000:], []byte("overwritten"))
+
+	sig, err := delta.NewSignature(bytes.NewReader(old), delta.BlockSizeFor(int64(len(old))))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := bytes.NewReader(old)
+	rebuilt := &bytes.Buffer{}
+	literal := 0
+	err = delta.Generate(sig, bytes.NewReader(updated), func(op *delta.Op) error {
+		if op.Type == delta.OpLiteral {
+			literal += len(op.Data)
+		}
+		return delta.Apply(base, sig.BlockSize, op, rebuilt)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(rebuilt.Bytes(), updated) {
+		t.Fatal("rebuilt file is not equal to the updated file")
+	}
+	if literal > 4*sig.BlockSize {
+		t.Fatalf("too many literal bytes: %d", literal)
+	}
+}
+
+func TestSendFileDelta(t *testing.T) {
+	rnd := rand.New(rand.NewSource(2))
+	old := make([]byte, 1<<20)
+	rnd.Read(old)
+	updated := append([]byte{}, old...)
+	copy(updated[300000:], []byte("changed on the sending side"))
+	updated = append(updated, []byte("appended")...)
+
+	dir := t.TempDir()
+	srcPath := filepath.Join(dir, "src")
+	dstPath := filepath.Join(dir, "dst")
+	err := os.WriteFile(srcPath, updated, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(dstPath, old, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	received := int64(0)
+	serverErr, clientErr := transact(t, 18096, func(conn *qp.Connection, stream *qp.Stream) error {
+		_, err := stream.RecvFileDelta(dstPath)
+		received = conn.Stats().BytesReceived
+		return err
+	}, func(conn *qp.Connection, stream *qp.Stream) error {
+		return stream.SendFileDelta(srcPath)
+	})
+	if serverErr != nil || clientErr != nil {
+		t.Fatal(serverErr, clientErr)
+	}
+
+	rebuilt, err := os.ReadFile(dstPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(rebuilt, updated) {
+		t.Fatal("received file is not equal to the sent file")
+	}
+	if received > int64(len(updated))/4 {
+		t.Fatalf("too many bytes are received for a delta: %d", received)
+	}
+}
+
+func TestRecvFileDeltaExceedingSize(t *testing.T) {
+	dir := t.TempDir()
+	srcPath := filepath.Join(dir, "src")
+	dstPath := filepath.Join(dir, "dst")
+	err := os.WriteFile(srcPath, []byte("ten bytes!"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The sender declares 10 bytes, but sends more than that before the end of the delta.
+	serverErr, _ := transact(t, 18111, func(conn *qp.Connection, stream *qp.Stream) error {
+		_, err := stream.RecvFileDelta(dstPath)
+		return err
+	}, func(conn *qp.Connection, stream *qp.Stream) error {
+		header, err := qpStream.ReadHeader(stream)
+		if err != nil {
+			return err
+		}
+		if header.RequestType != pb.RequestType_DELTA_SIGNATURE {
+			return errors.New("request type is not DeltaSignature")
+		}
+		_, err = qpStream.ReadDeltaSignature(stream)
+		if err != nil {
+			return err
+		}
+		osFileInfo, err := os.Stat(srcPath)
+		if err != nil {
+			return err
+		}
+		fileInfo, err := fileinfo.NewFromOSFileInfo(osFileInfo)
+		if err != nil {
+			return err
+		}
+		requestId, err := uuid.New().MarshalBinary()
+		if err != nil {
+			return err
+		}
+		err = qpStream.WriteHeader(stream, pb.RequestType_FILE_DELTA, requestId, "")
+		if err != nil {
+			return err
+		}
+		err = qpStream.WriteFileInfo(stream, fileInfo)
+		if err != nil {
+			return err
+		}
+		for i := 0; i < 4; i++ {
+			err = qpStream.WriteDeltaOp(stream, &delta.Op{Type: delta.OpLiteral, Data: make([]byte, 1<<20)})
+			if err != nil {
+				return err
+			}
+		}
+		return qpStream.WriteDeltaEnd(stream, nil)
+	})
+
+	limitErr := &qp.LimitError{}
+	if !errors.As(serverErr, &limitErr) || limitErr.Max != 10 {
+		t.Fatalf("limit error is expected: %v", serverErr)
+	}
+	if _, err := os.Stat(dstPath); !os.IsNotExist(err) {
+		t.Fatalf("file exceeding the declared size is written: %v", err)
+	}
+}
@@ -0,0 +1,122 @@
+package main_test
+
+import (
+	"bytes"
+	"io/fs"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	qp "github.com/quic-s/quics-protocol"
+)
+
+func TestSendDirProgress(t *testing.T) {
+	rnd := rand.New(rand.NewSource(4))
+	big := make([]byte, 300000)
+	rnd.Read(big)
+
+	srcDir := t.TempDir()
+	dstDir := filepath.Join(t.TempDir(), "dst")
+	files := map[string][]byte{
+		"a.txt":           []byte("hello, quics-protocol"),
+		"sub/big.bin":     big,
+		"sub/deeper/c":    []byte("c"),
+		"sub/deeper/zero": {},
+	}
+	for name, data := range files {
+		err := os.MkdirAll(filepath.Join(srcDir, filepath.Dir(name)), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.WriteFile(filepath.Join(srcDir, name), data, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := os.Mkdir(filepath.Join(srcDir, "empty"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	totalSize := int64(0)
+	for _, data := range files {
+		totalSize += int64(len(data))
+	}
+	// The files and the directories sub, sub/deeper and empty.
+	totalEntries := len(files) + 3
+
+	checkProgress := func(side string, reports []qp.Progress) {
+		if len(reports) == 0 {
+			t.Fatal(side, "progress is not reported")
+		}
+		finished := 0
+		for _, p := range reports {
+			if p.Total != totalSize || p.EntriesTotal != totalEntries {
+				t.Fatal(side, "progress has wrong totals:", p)
+			}
+			if p.Finished {
+				finished++
+				if p.EntryDone != p.EntryTotal {
+					t.Fatal(side, "finished entry is not complete:", p)
+				}
+			}
+		}
+		if finished != totalEntries {
+			t.Fatal(side, "finished entries:", finished)
+		}
+		last := reports[len(reports)-1]
+		if last.Done != totalSize || last.EntriesDone != totalEntries || last.ETA != 0 {
+			t.Fatal(side, "last progress is not complete:", last)
+		}
+	}
+
+	sent := []qp.Progress{}
+	received := []qp.Progress{}
+	serverErr, clientErr := transact(t, 18100, func(conn *qp.Connection, stream *qp.Stream) error {
+		stream.SetProgressFunc(time.Millisecond, func(progress qp.Progress) {
+			received = append(received, progress)
+		})
+		_, err := stream.RecvDir(dstDir)
+		return err
+	}, func(conn *qp.Connection, stream *qp.Stream) error {
+		stream.SetProgressFunc(time.Millisecond, func(progress qp.Progress) {
+			sent = append(sent, progress)
+		})
+		return stream.SendDir(srcDir)
+	})
+	if serverErr != nil || clientErr != nil {
+		t.Fatal(serverErr, clientErr)
+	}
+	checkProgress("sending side", sent)
+	checkProgress("receiving side", received)
+
+	entries := 0
+	err = filepath.WalkDir(dstDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || path == dstDir {
+			return err
+		}
+		entries++
+		rel, err := filepath.Rel(dstDir, path)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(data, files[filepath.ToSlash(rel)]) {
+			t.Fatal("received file is not equal to the sent file:", rel)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entries != totalEntries {
+		t.Fatal("received entries:", entries)
+	}
+}
@@ -0,0 +1,84 @@
+package main_test
+
+import (
+	"crypto/tls"
+	"errors"
+	"testing"
+	"time"
+
+	qp "github.com/quic-s/quics-protocol"
+)
+
+func TestEnrollment(t *testing.T) {
+	ca, err := qp.NewRootCA(qp.CertificateOptions{CommonName: "root", Validity: 24 * time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverCert, err := ca.IssueServer(qp.CertificateOptions{CommonName: "server", Hosts: []string{"localhost"}, Validity: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens := qp.NewBootstrapTokens()
+	tokens.Add("bootstrap token", time.Minute)
+
+	server, err := qp.New(qp.LOG_LEVEL_ERROR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.SetEnrollment(qp.Enrollment{
+		CA: ca,
+		Approve: func(request *qp.EnrollmentRequest) (qp.CertificateOptions, error) {
+			commonName := request.CertificateRequest.Subject.CommonName
+			if request.Peer != nil && request.Peer.Subject.CommonName == commonName {
+				return request.Options(time.Hour), nil
+			}
+			if request.Token != "" && tokens.Use(request.Token) {
+				return request.Options(time.Hour), nil
+			}
+			return qp.CertificateOptions{}, errors.New("invalid bootstrap token")
+		},
+	})
+	serverConf := ca.ServerTLSConfig(serverCert)
+	serverConf.ClientAuth = tls.VerifyClientCertIfGiven
+	go server.Listen(":18090", serverConf, func(conn *qp.Connection) {})
+	defer server.Close()
+	time.Sleep(200 * time.Millisecond)
+
+	dial := func(cert *tls.Certificate) *qp.Connection {
+		client, err := qp.New(qp.LOG_LEVEL_ERROR)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn, err := client.Dial("localhost", 18090, ca.ClientTLSConfig(cert, "localhost"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return conn
+	}
+
+	conn := dial(nil)
+	defer conn.Close()
+	opts := qp.CertificateOptions{CommonName: "device-1"}
+	cert, err := conn.Enroll("bootstrap token", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cert.Leaf.Subject.CommonName != "device-1" {
+		t.Fatalf("certificate of %s is issued", cert.Leaf.Subject.CommonName)
+	}
+	_, err = conn.Enroll("bootstrap token", qp.CertificateOptions{CommonName: "device-2"})
+	if err == nil {
+		t.Fatal("bootstrap token is used twice")
+	}
+
+	// Renew the certificate over a connection authenticated by it.
+	conn = dial(cert)
+	defer conn.Close()
+	renewed, err := conn.Enroll("", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if renewed.Leaf.Subject.CommonName != "device-1" || qp.Fingerprint(renewed.Leaf) == qp.Fingerprint(cert.Leaf) {
+		t.Fatal("certificate is not renewed with a new key")
+	}
+}
@@ -0,0 +1,203 @@
+package main_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	qp "github.com/quic-s/quics-protocol"
+	"github.com/quic-s/quics-protocol/pkg/types/fileinfo"
+)
+
+type failingReader struct{}
+
+func (failingReader) Read(p []byte) (int, error) {
+	return 0, errors.New("connection lost")
+}
+
+func TestWriteFileWithInfoAtomic(t *testing.T) {
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "file.txt")
+	err := os.WriteFile(filePath, []byte("previous"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fileInfo := &qp.FileInfo{
+		Name:    "file.txt",
+		Size:    7,
+		Mode:    0640,
+		ModTime: time.Now().Add(-time.Hour).Truncate(time.Second),
+	}
+
+	// The previous file must be kept when the content fails halfway.
+	err = fileInfo.WriteFileWithInfo(filePath, io.MultiReader(bytes.NewReader([]byte("upd")), failingReader{}))
+	if err == nil {
+		t.Fatal("error is expected")
+	}
+	content, _ := os.ReadFile(filePath)
+	if string(content) != "previous" {
+		t.Fatalf("previous file is not kept: %q", content)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Fatalf("temporary file is not removed: %d entries", len(entries))
+	}
+
+	err = fileInfo.WriteFileWithOptions(filePath, bytes.NewReader([]byte("updated")), &fileinfo.WriteOptions{Backup: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ = os.ReadFile(filePath)
+	if string(content) != "updated" {
+		t.Fatalf("file is not updated: %q", content)
+	}
+	backup, _ := os.ReadFile(filePath + fileinfo.DefaultBackupSuffix)
+	if string(backup) != "previous" {
+		t.Fatalf("backup is not kept: %q", backup)
+	}
+	stat, _ := os.Stat(filePath)
+	if stat.Mode() != 0640 || !stat.ModTime().Equal(fileInfo.ModTime) {
+		t.Fatalf("metadata is not set: %s %s", stat.Mode(), stat.ModTime())
+	}
+}
+
+func TestWriteFileWithOptionsConflict(t *testing.T) {
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "file.txt")
+	err := os.WriteFile(filePath, []byte("base"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := &fileinfo.BaseState{Hash: sha256Sum([]byte("base"))}
+
+	// The local file is changed after the base state.
+	err = os.WriteFile(filePath, []byte("local"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fileInfo := &qp.FileInfo{
+		Name:    "file.txt",
+		Size:    8,
+		Mode:    0600,
+		ModTime: time.Now().Truncate(time.Second),
+	}
+	err = fileInfo.WriteFileWithOptions(filePath, bytes.NewReader([]byte("received")), &fileinfo.WriteOptions{
+		ExpectedBase:   base,
+		ConflictPolicy: fileinfo.ConflictFail,
+	})
+	conflict := &fileinfo.ConflictError{}
+	if !errors.As(err, &conflict) {
+		t.Fatalf("conflict error is expected: %v", err)
+	}
+	content, _ := os.ReadFile(filePath)
+	if string(content) != "local" {
+		t.Fatalf("local file is not kept: %q", content)
+	}
+
+	// Both files are kept. A second copy made in the same second does not replace the first one.
+	copyPaths := []string{}
+	for i := 0; i < 2; i++ {
+		err = fileInfo.WriteFileWithOptions(filePath, bytes.NewReader([]byte("received")), &fileinfo.WriteOptions{
+			ExpectedBase: base,
+			ConflictResolver: func(conflict *fileinfo.ConflictError) fileinfo.ConflictPolicy {
+				return fileinfo.ConflictKeepBoth
+			},
+			KeepBothFunc: func(conflict *fileinfo.ConflictError) {
+				copyPaths = append(copyPaths, conflict.CopyPath)
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(copyPaths) != 2 || copyPaths[0] == copyPaths[1] {
+		t.Fatalf("copy paths are not reported: %q", copyPaths)
+	}
+	content, _ = os.ReadFile(filePath)
+	if string(content) != "local" {
+		t.Fatalf("local file is not kept: %q", content)
+	}
+	for _, copyPath := range copyPaths {
+		copied, _ := os.ReadFile(copyPath)
+		if string(copied) != "received" {
+			t.Fatalf("received file is not kept: %q", copied)
+		}
+	}
+
+	// A size of 0 is compared too.
+	zero := int64(0)
+	err = fileInfo.WriteFileWithOptions(filePath, bytes.NewReader([]byte("received")), &fileinfo.WriteOptions{
+		ExpectedBase:   &fileinfo.BaseState{Size: &zero},
+		ConflictPolicy: fileinfo.ConflictFail,
+	})
+	if !errors.As(err, &conflict) {
+		t.Fatalf("conflict error is expected: %v", err)
+	}
+
+	// Without a conflict, the file is replaced.
+	stat, err := os.Stat(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	localInfo, err := fileinfo.NewFromOSFileInfo(stat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	localBase := fileinfo.NewBaseState(localInfo)
+	localBase.Hash = sha256Sum([]byte("local"))
+	err = fileInfo.WriteFileWithOptions(filePath, bytes.NewReader([]byte("received")), &fileinfo.WriteOptions{
+		ExpectedBase:   localBase,
+		ConflictPolicy: fileinfo.ConflictFail,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ = os.ReadFile(filePath)
+	if string(content) != "received" {
+		t.Fatalf("file is not replaced: %q", content)
+	}
+}
+
+func sha256Sum(data []byte) []byte {
+	hash := sha256.Sum256(data)
+	return hash[:]
+}
+
+func TestSparseReader(t *testing.T) {
+	extents := []fileinfo.Extent{{Offset: 2, Length: 3}, {Offset: 8, Length: 2}}
+	expected := []byte{0, 0, 'a', 'b', 'c', 0, 0, 0, 'd', 'e', 0, 0}
+
+	// Receivers reading the content as a whole get zeros for the holes.
+	content, err := io.ReadAll(fileinfo.NewSparseReader(bytes.NewReader([]byte("abcde")), extents, 12))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(content, expected) {
+		t.Fatalf("content is not expanded: %v", content)
+	}
+
+	filePath := filepath.Join(t.TempDir(), "sparse.img")
+	fileInfo := &qp.FileInfo{
+		Name:    "sparse.img",
+		Size:    12,
+		Mode:    0600,
+		ModTime: time.Now().Truncate(time.Second),
+		Sparse:  true,
+		Extents: extents,
+	}
+	err = fileInfo.WriteFileWithInfo(filePath, fileinfo.NewSparseReader(bytes.NewReader([]byte("abcde")), extents, 12))
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ = os.ReadFile(filePath)
+	if !bytes.Equal(content, expected) {
+		t.Fatalf("sparse file is not written: %v", content)
+	}
+}
@@ -0,0 +1,84 @@
+package main_test
+
+import (
+	"crypto/tls"
+	"testing"
+	"time"
+
+	qp "github.com/quic-s/quics-protocol"
+)
+
+func TestHello(t *testing.T) {
+	server, err := qp.New(qp.LOG_LEVEL_ERROR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.SetCapabilities(qp.Capabilities{
+		Compression: []string{"zstd"},
+		Checksums:   []string{"sha256"},
+	})
+	peerCapabilities := make(chan qp.Capabilities, 1)
+	err = server.RecvTransactionHandleFunc("capabilities", func(conn *qp.Connection, stream *qp.Stream, transactionName string, transactionID []byte) error {
+		capabilities, ok := conn.PeerCapabilities()
+		if !ok {
+			t.Error("capabilities of the client are not received")
+		}
+		peerCapabilities <- capabilities
+		return stream.SendBMessage([]byte("ok"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := qp.GetCertificate("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Listen(":18091", &tls.Config{Certificates: cert, NextProtos: []string{"quics-protocol"}}, func(conn *qp.Connection) {})
+	defer server.Close()
+	time.Sleep(200 * time.Millisecond)
+
+	client, err := qp.New(qp.LOG_LEVEL_ERROR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetCapabilities(qp.Capabilities{
+		Compression: []string{"gzip", "zstd"},
+		Checksums:   []string{"sha256"},
+		Features:    []string{"resume"},
+	})
+	conn, err := client.Dial("localhost", 18091, &tls.Config{InsecureSkipVerify: true, NextProtos: []string{"quics-protocol"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if conn.Version() != qp.ProtocolVersion {
+		t.Fatalf("version %d is expected, but got %d", qp.ProtocolVersion, conn.Version())
+	}
+	capabilities, ok := conn.PeerCapabilities()
+	if !ok {
+		t.Fatal("capabilities of the server are not received")
+	}
+	if capabilities.Version != qp.ProtocolVersion {
+		t.Fatalf("server version %d is expected, but got %d", qp.ProtocolVersion, capabilities.Version)
+	}
+	negotiated := qp.Negotiate(conn.Capabilities(), capabilities)
+	if len(negotiated.Compression) != 1 || negotiated.Compression[0] != "zstd" {
+		t.Fatalf("compression zstd is expected, but got %v", negotiated.Compression)
+	}
+	if len(negotiated.Features) != 0 {
+		t.Fatalf("no common feature is expected, but got %v", negotiated.Features)
+	}
+
+	err = conn.OpenTransaction("capabilities", func(stream *qp.Stream, transactionName string, transactionID []byte) error {
+		_, err := stream.RecvBMessage()
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	capabilities = <-peerCapabilities
+	if len(capabilities.Features) != 1 || capabilities.Features[0] != "resume" {
+		t.Fatalf("feature resume of the client is expected on the server, but got %v", capabilities.Features)
+	}
+}
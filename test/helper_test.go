@@ -0,0 +1,62 @@
+package main_test
+
+import (
+	"crypto/tls"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	qp "github.com/quic-s/quics-protocol"
+)
+
+// transact runs a transaction between a new server listening on the port and a new client.
+// The server handles the transaction with handle, and the client opens it with open.
+// It returns the errors of both sides.
+func transact(t *testing.T, port int, handle func(conn *qp.Connection, stream *qp.Stream) error, open func(conn *qp.Connection, stream *qp.Stream) error) (error, error) {
+	t.Helper()
+	server, err := qp.New(qp.LOG_LEVEL_ERROR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverErr := make(chan error, 1)
+	err = server.RecvTransactionHandleFunc("transact", func(conn *qp.Connection, stream *qp.Stream, transactionName string, transactionID []byte) error {
+		err := handle(conn, stream)
+		serverErr <- err
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := qp.GetCertificate("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Listen(addr(port), &tls.Config{Certificates: cert, NextProtos: []string{"quics-protocol"}}, func(conn *qp.Connection) {})
+	defer server.Close()
+	time.Sleep(200 * time.Millisecond)
+
+	client, err := qp.New(qp.LOG_LEVEL_ERROR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := client.Dial("localhost", port, &tls.Config{InsecureSkipVerify: true, NextProtos: []string{"quics-protocol"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	clientErr := conn.OpenTransaction("transact", func(stream *qp.Stream, transactionName string, transactionID []byte) error {
+		return open(conn, stream)
+	})
+	select {
+	case err := <-serverErr:
+		return err, clientErr
+	case <-time.After(5 * time.Second):
+		return errors.New("transaction is not handled by the server"), clientErr
+	}
+}
+
+func addr(port int) string {
+	return ":" + strconv.Itoa(port)
+}
@@ -0,0 +1,136 @@
+package main_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	qp "github.com/quic-s/quics-protocol"
+)
+
+// newClientCertificate creates a CA and a client certificate signed by it.
+func newClientCertificate(t *testing.T) (*x509.CertPool, tls.Certificate) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "client"},
+		DNSNames:     []string{"client.example"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+	return pool, tls.Certificate{Certificate: [][]byte{certDER}, PrivateKey: key}
+}
+
+func TestPeerIdentity(t *testing.T) {
+	pool, clientCert := newClientCertificate(t)
+	leaf, err := x509.ParseCertificate(clientCert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server, err := qp.New(qp.LOG_LEVEL_ERROR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revocation := qp.NewRevocation()
+	server.SetClientCAs(pool)
+	server.SetAdmission(qp.Admission{Revocation: revocation})
+	cert, err := qp.GetCertificate("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	identities := make(chan *qp.Identity, 1)
+	go server.Listen(":18085", &tls.Config{Certificates: cert, NextProtos: []string{"quics-protocol"}}, func(conn *qp.Connection) {
+		identities <- conn.PeerIdentity()
+	})
+	defer server.Close()
+	time.Sleep(200 * time.Millisecond)
+
+	dial := func(certs []tls.Certificate) (*qp.Connection, error) {
+		client, err := qp.New(qp.LOG_LEVEL_ERROR)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return client.Dial("localhost", 18085, &tls.Config{InsecureSkipVerify: true, Certificates: certs, NextProtos: []string{"quics-protocol"}})
+	}
+
+	conn, err := dial([]tls.Certificate{clientCert})
+	if err != nil {
+		t.Fatal(err)
+	}
+	identity := <-identities
+	if identity == nil || identity.Subject.CommonName != "client" || len(identity.DNSNames) != 1 || identity.DNSNames[0] != "client.example" {
+		t.Fatalf("wrong peer identity: %+v", identity)
+	}
+	if identity.Fingerprint != qp.Fingerprint(leaf) {
+		t.Fatalf("fingerprint %s is expected, but got %s", qp.Fingerprint(leaf), identity.Fingerprint)
+	}
+	conn.Close()
+
+	// A client without a certificate fails the TLS handshake.
+	conn, err = dial(nil)
+	if err == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		_, err = conn.Conn.AcceptStream(ctx)
+		if errors.Is(err, context.DeadlineExceeded) {
+			t.Fatal("connection without a client certificate is accepted")
+		}
+	}
+
+	revocation.Ban(identity.Fingerprint)
+	conn, err = dial([]tls.Certificate{clientCert})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, err = conn.Conn.AcceptStream(ctx)
+	appErr := &quic.ApplicationError{}
+	if !errors.As(err, &appErr) || appErr.ErrorCode != qp.ConnectionRejectedCode {
+		t.Fatalf("connection with a banned certificate is not rejected: %v", err)
+	}
+}
@@ -0,0 +1,120 @@
+package main_test
+
+import (
+	"crypto/tls"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	qp "github.com/quic-s/quics-protocol"
+)
+
+func TestKnownHosts(t *testing.T) {
+	server, err := qp.New(qp.LOG_LEVEL_ERROR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := qp.GetCertificate("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Listen(":18088", &tls.Config{Certificates: cert, NextProtos: []string{"quics-protocol"}}, func(conn *qp.Connection) {})
+	defer server.Close()
+	time.Sleep(200 * time.Millisecond)
+
+	path := filepath.Join(t.TempDir(), "known_hosts")
+	knownHosts, err := qp.LoadKnownHosts(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dial := func(knownHosts *qp.KnownHosts) error {
+		client, err := qp.New(qp.LOG_LEVEL_ERROR)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn, err := client.Dial("localhost", 18088, knownHosts.ClientTLSConfig("localhost:18088"))
+		if err == nil {
+			conn.Close()
+		}
+		return err
+	}
+
+	// The first connection pins the certificate, and the later connections are checked with the file.
+	err = dial(knownHosts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reloaded, err := qp.LoadKnownHosts(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fingerprints := reloaded.Fingerprints("localhost:18088")
+	if len(fingerprints) != 1 || fingerprints[0] != qp.Fingerprint(cert[0].Leaf) {
+		t.Fatalf("fingerprint of the server is not pinned: %v", fingerprints)
+	}
+	err = dial(reloaded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = reloaded.Unpin("localhost:18088")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = reloaded.Pin("localhost:18088", strings.Repeat("0", 64))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = dial(reloaded)
+	mismatch := &qp.HostKeyMismatchError{}
+	if !errors.As(err, &mismatch) || mismatch.Received != qp.Fingerprint(cert[0].Leaf) {
+		t.Fatalf("host key mismatch is expected: %v", err)
+	}
+
+	// The values that cannot be loaded from the known hosts file are not pinned.
+	for _, invalid := range [][2]string{
+		{"localhost:18088", "0000"},
+		{"localhost:18088", strings.Repeat("0", 63) + "\n"},
+		{"localhost:18088", strings.Repeat("z", 64)},
+		{"local host:18088", strings.Repeat("0", 64)},
+		{"localhost:18088\n", strings.Repeat("0", 64)},
+		{"", strings.Repeat("0", 64)},
+	} {
+		err = reloaded.Pin(invalid[0], invalid[1])
+		if !errors.Is(err, qp.ErrInvalidKnownHost) {
+			t.Fatalf("invalid known host %q is pinned: %v", invalid, err)
+		}
+	}
+	_, err = qp.LoadKnownHosts(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// If the known hosts file cannot be written, the server is not pinned and the connection fails.
+	dir := filepath.Join(t.TempDir(), "unwritable")
+	unwritable, err := qp.LoadKnownHosts(filepath.Join(dir, "known_hosts"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(dir, nil, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = dial(unwritable)
+	if err == nil {
+		t.Fatal("connection must fail if the known hosts file cannot be written")
+	}
+	if fingerprints := unwritable.Fingerprints("localhost:18088"); len(fingerprints) != 0 {
+		t.Fatalf("fingerprint is pinned without being written: %v", fingerprints)
+	}
+	err = unwritable.Pin("localhost:18088", qp.Fingerprint(cert[0].Leaf))
+	if err == nil {
+		t.Fatal("pin must fail if the known hosts file cannot be written")
+	}
+	if fingerprints := unwritable.Fingerprints("localhost:18088"); len(fingerprints) != 0 {
+		t.Fatalf("fingerprint is pinned without being written: %v", fingerprints)
+	}
+}
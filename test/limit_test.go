@@ -0,0 +1,118 @@
+package main_test
+
+import (
+	"crypto/tls"
+	"errors"
+	"testing"
+	"time"
+
+	qp "github.com/quic-s/quics-protocol"
+)
+
+func TestReceiveLimits(t *testing.T) {
+	server, err := qp.New(qp.LOG_LEVEL_ERROR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recvErr := make(chan error, 1)
+	err = server.RecvTransactionHandleFunc("limit", func(conn *qp.Connection, stream *qp.Stream, transactionName string, transactionID []byte) error {
+		// The limit of the transaction is not sent to the client, so the client sends the message anyway.
+		stream.SetLimits(qp.Limits{MaxMessageSize: 1024})
+		_, err := stream.RecvBMessage()
+		recvErr <- err
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := qp.GetCertificate("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Listen(":18081", &tls.Config{Certificates: cert, NextProtos: []string{"quics-protocol"}}, func(conn *qp.Connection) {})
+	defer server.Close()
+	time.Sleep(200 * time.Millisecond)
+
+	client, err := qp.New(qp.LOG_LEVEL_ERROR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := client.Dial("localhost", 18081, &tls.Config{InsecureSkipVerify: true, NextProtos: []string{"quics-protocol"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.OpenTransaction("limit", func(stream *qp.Stream, transactionName string, transactionID []byte) error {
+		return stream.SendBMessage(make([]byte, 4096))
+	})
+
+	limitErr := &qp.LimitError{}
+	if err := <-recvErr; !errors.As(err, &limitErr) {
+		t.Fatalf("limit error is expected: %v", err)
+	}
+	if limitErr.Value != 4096 || limitErr.Max != 1024 {
+		t.Fatalf("unexpected limit error: %v", limitErr)
+	}
+}
+
+func TestSendLimits(t *testing.T) {
+	server, err := qp.New(qp.LOG_LEVEL_ERROR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.SetLimits(qp.Limits{MaxMessageSize: 1024})
+	received := make(chan []byte, 1)
+	err = server.RecvTransactionHandleFunc("limit", func(conn *qp.Connection, stream *qp.Stream, transactionName string, transactionID []byte) error {
+		data, err := stream.RecvBMessage()
+		received <- data
+		if err != nil {
+			return err
+		}
+		// The own maximum message size does not limit the messages sent to the client.
+		return stream.SendBMessage(make([]byte, 4096))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := qp.GetCertificate("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Listen(":18109", &tls.Config{Certificates: cert, NextProtos: []string{"quics-protocol"}}, func(conn *qp.Connection) {})
+	defer server.Close()
+	time.Sleep(200 * time.Millisecond)
+
+	client, err := qp.New(qp.LOG_LEVEL_ERROR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := client.Dial("localhost", 18109, &tls.Config{InsecureSkipVerify: true, NextProtos: []string{"quics-protocol"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// The message larger than the maximum message size of the server is not sent, and the transaction can go on.
+	err = conn.OpenTransaction("limit", func(stream *qp.Stream, transactionName string, transactionID []byte) error {
+		limitErr := &qp.LimitError{}
+		err := stream.SendBMessage(make([]byte, 4096))
+		if !errors.As(err, &limitErr) || limitErr.Value != 4096 || limitErr.Max != 1024 {
+			t.Errorf("limit error is expected: %v", err)
+		}
+		err = stream.SendBMessage(make([]byte, 1024))
+		if err != nil {
+			return err
+		}
+		data, err := stream.RecvBMessage()
+		if err == nil && len(data) != 4096 {
+			t.Errorf("message of 4096 bytes is expected, but got %d bytes", len(data))
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data := <-received; len(data) != 1024 {
+		t.Fatalf("message of 1024 bytes is expected, but got %d bytes", len(data))
+	}
+}
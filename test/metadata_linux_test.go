@@ -0,0 +1,187 @@
+package main_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	qp "github.com/quic-s/quics-protocol"
+	"github.com/quic-s/quics-protocol/pkg/types/fileinfo"
+)
+
+func TestSendDirMetadata(t *testing.T) {
+	srcDir := t.TempDir()
+	dstDir := filepath.Join(t.TempDir(), "dst")
+	filePath := filepath.Join(srcDir, "file")
+	err := os.WriteFile(filePath, []byte("metadata"), 0640)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Link(filePath, filepath.Join(srcDir, "hardlink"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Symlink("file", filepath.Join(srcDir, "symlink"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = syscall.Mkfifo(filepath.Join(srcDir, "fifo"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	xattr := syscall.Setxattr(filePath, "user.quics", []byte("value"), 0) == nil
+	if !xattr {
+		t.Log("extended attributes are not supported by the file system")
+	}
+	owner := os.Getuid() == 0
+	if owner {
+		err = os.Chown(filePath, 1234, 2345)
+		if err != nil {
+			t.Fatal(err)
+		}
+	} else {
+		t.Log("owner is not checked without root")
+	}
+
+	// The owner names of the test ids may not exist, so only the ids are sent.
+	metadata := fileinfo.MetadataAll &^ fileinfo.MetadataOwnerName
+	serverErr, clientErr := transact(t, 18101, func(conn *qp.Connection, stream *qp.Stream) error {
+		_, err := stream.RecvDirWithOptions(dstDir, &fileinfo.WriteOptions{Apply: metadata})
+		return err
+	}, func(conn *qp.Connection, stream *qp.Stream) error {
+		stream.SetMetadata(metadata)
+		return stream.SendDir(srcDir)
+	})
+	if serverErr != nil || clientErr != nil {
+		t.Fatal(serverErr, clientErr)
+	}
+
+	file, err := os.Lstat(filepath.Join(dstDir, "file"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file.Mode() != 0640 {
+		t.Fatal("mode is not kept:", file.Mode())
+	}
+	hardlink, err := os.Lstat(filepath.Join(dstDir, "hardlink"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !os.SameFile(file, hardlink) {
+		t.Fatal("hard link is not kept")
+	}
+	target, err := os.Readlink(filepath.Join(dstDir, "symlink"))
+	if err != nil || target != "file" {
+		t.Fatal("symbolic link is not kept:", target, err)
+	}
+	fifo, err := os.Lstat(filepath.Join(dstDir, "fifo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fifo.Mode()&os.ModeNamedPipe == 0 || fifo.Mode().Perm() != 0600 {
+		t.Fatal("named pipe is not kept:", fifo.Mode())
+	}
+	if xattr {
+		value := make([]byte, 16)
+		n, err := syscall.Getxattr(filepath.Join(dstDir, "file"), "user.quics", value)
+		if err != nil || string(value[:n]) != "value" {
+			t.Fatal("extended attribute is not kept:", string(value[:n]), err)
+		}
+	}
+	if owner {
+		stat := file.Sys().(*syscall.Stat_t)
+		if stat.Uid != 1234 || stat.Gid != 2345 {
+			t.Fatal("owner is not kept:", stat.Uid, stat.Gid)
+		}
+	}
+}
+
+func TestSendFileSparse(t *testing.T) {
+	dir := t.TempDir()
+	srcPath := filepath.Join(dir, "src.img")
+	dstPath := filepath.Join(dir, "dst.img")
+	src, err := os.Create(srcPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	const size = 4 << 20
+	err = src.Truncate(size)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := bytes.Repeat([]byte("sparse"), 1000)
+	for _, offset := range []int64{0, 2 << 20} {
+		_, err = src.WriteAt(data, offset)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	extents, err := fileinfo.DataExtents(src, size)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if extents == nil {
+		t.Skip("holes are not supported by the file system")
+	}
+
+	serverErr, clientErr := transact(t, 18102, func(conn *qp.Connection, stream *qp.Stream) error {
+		fileInfo, content, err := stream.RecvFile()
+		if err != nil {
+			return err
+		}
+		if !fileInfo.Sparse {
+			return errors.New("file is not sent as sparse")
+		}
+		return fileInfo.WriteFileWithInfo(dstPath, content)
+	}, func(conn *qp.Connection, stream *qp.Stream) error {
+		stream.SetMetadata(fileinfo.MetadataSparse)
+		return stream.SendFile(srcPath)
+	})
+	if serverErr != nil || clientErr != nil {
+		t.Fatal(serverErr, clientErr)
+	}
+
+	expected, err := os.ReadFile(srcPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	received, err := os.ReadFile(dstPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(received, expected) {
+		t.Fatal("received file is not equal to the sent file")
+	}
+
+	// The holes are recreated, so only the data extents are allocated.
+	dst, err := os.Open(dstPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dst.Close()
+	dstExtents, err := fileinfo.DataExtents(dst, size)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dstExtents == nil {
+		t.Fatal("holes are not recreated")
+	}
+	allocated := int64(0)
+	for _, extent := range dstExtents {
+		allocated += extent.Length
+	}
+	if allocated >= size/2 {
+		t.Fatal("too much data is allocated:", allocated)
+	}
+	stat, err := dst.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if blocks := stat.Sys().(*syscall.Stat_t).Blocks * 512; blocks >= size/2 {
+		t.Fatal("too many blocks are allocated:", blocks)
+	}
+}
@@ -0,0 +1,167 @@
+package main_test
+
+import (
+	"crypto/tls"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	qp "github.com/quic-s/quics-protocol"
+	qpStream "github.com/quic-s/quics-protocol/pkg/stream"
+	pb "github.com/quic-s/quics-protocol/proto/v1"
+)
+
+func TestMetrics(t *testing.T) {
+	prometheus := qp.NewPrometheus()
+	server, err := qp.New(qp.LOG_LEVEL_ERROR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.SetMetrics(prometheus)
+	err = server.RecvTransactionHandleFunc("echo", func(conn *qp.Connection, stream *qp.Stream, transactionName string, transactionID []byte) error {
+		data, err := stream.RecvBMessage()
+		if err != nil {
+			return err
+		}
+		return stream.SendBMessage(data)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = server.RecvTransactionHandleFunc("fail", func(conn *qp.Connection, stream *qp.Stream, transactionName string, transactionID []byte) error {
+		return errors.New("failed on purpose")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := qp.GetCertificate("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Listen(":18095", &tls.Config{Certificates: cert, NextProtos: []string{"quics-protocol"}}, func(conn *qp.Connection) {})
+	defer server.Close()
+	time.Sleep(200 * time.Millisecond)
+
+	client, err := qp.New(qp.LOG_LEVEL_ERROR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := client.Dial("localhost", 18095, &tls.Config{InsecureSkipVerify: true, NextProtos: []string{"quics-protocol"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = conn.OpenTransaction("echo", func(stream *qp.Stream, transactionName string, transactionID []byte) error {
+		err := stream.SendBMessage(make([]byte, 1000))
+		if err != nil {
+			return err
+		}
+		_, err = stream.RecvBMessage()
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = conn.OpenTransaction("fail", func(stream *qp.Stream, transactionName string, transactionID []byte) error {
+		_, err := stream.RecvBMessage()
+		return err
+	})
+	if err == nil {
+		t.Fatal("error of the handler is not received")
+	}
+	// The transaction without its own handler is reported by the name of the default handler.
+	err = conn.OpenTransaction("unregistered", func(stream *qp.Stream, transactionName string, transactionID []byte) error {
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = conn.OpenTransaction("quics-protocol:bogus", func(stream *qp.Stream, transactionName string, transactionID []byte) error {
+		_, err := stream.RecvBMessage()
+		return err
+	})
+	if err == nil {
+		t.Fatal("unknown reserved transaction is not refused")
+	}
+	conn.Close()
+
+	expected := []string{
+		"# TYPE quics_connections_accepted_total counter",
+		"quics_connections_accepted_total 1",
+		"quics_connections_closed_total 1",
+		`quics_transactions_total{name="echo",outcome="ok"} 1`,
+		`quics_transactions_total{name="fail",outcome="error"} 1`,
+		`quics_transactions_total{name="default",outcome="ok"} 1`,
+		`quics_transactions_total{name="quics-protocol:unknown",outcome="refused"} 1`,
+		"# TYPE quics_handler_duration_seconds histogram",
+		`quics_handler_duration_seconds_bucket{name="echo",le="+Inf"} 1`,
+		`quics_handler_duration_seconds_count{name="echo"} 1`,
+		`quics_bytes_total{direction="received",request_type="BMESSAGE"}`,
+		`quics_bytes_total{direction="sent",request_type="BMESSAGE"}`,
+	}
+	scrape := func() string {
+		recorder := httptest.NewRecorder()
+		prometheus.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+		if contentType := recorder.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "text/plain; version=0.0.4") {
+			t.Fatalf("wrong content type %q", contentType)
+		}
+		body, err := io.ReadAll(recorder.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(body)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for _, line := range expected {
+		for body := scrape(); !strings.Contains(body, line); body = scrape() {
+			if time.Now().After(deadline) {
+				t.Fatalf("%q is not exposed:\n%s", line, body)
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+
+	if body := scrape(); strings.Contains(body, "unregistered") || strings.Contains(body, "bogus") {
+		t.Fatalf("names of the transactions sent by the peer are exposed:\n%s", body)
+	}
+
+	prometheus.TransactionHandled("quoted \"name\"\n", qp.OutcomeRefused, 0)
+	prometheus.Error(qp.UnauthenticatedCode)
+	body := scrape()
+	for _, line := range []string{
+		`quics_transactions_total{name="quoted \"name\"\n",outcome="refused"} 1`,
+		`quics_errors_total{code="0x7"} 1`,
+	} {
+		if !strings.Contains(body, line) {
+			t.Fatalf("%q is not exposed:\n%s", line, body)
+		}
+	}
+}
+
+func TestMetricsUnknownRequestType(t *testing.T) {
+	prometheus := qp.NewPrometheus()
+	serverErr, _ := transact(t, 18119, func(conn *qp.Connection, stream *qp.Stream) error {
+		stream.SetMetrics(prometheus)
+		_, err := stream.RecvBMessage()
+		return err
+	}, func(conn *qp.Connection, stream *qp.Stream) error {
+		requestId, err := uuid.New().MarshalBinary()
+		if err != nil {
+			return err
+		}
+		return qpStream.WriteHeader(stream, pb.RequestType(12345), requestId, "")
+	})
+	if serverErr == nil {
+		t.Fatal("unknown request type is accepted")
+	}
+
+	recorder := httptest.NewRecorder()
+	prometheus.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	body := recorder.Body.String()
+	if !strings.Contains(body, `quics_bytes_total{direction="received",request_type="unknown"}`) || strings.Contains(body, "12345") {
+		t.Fatalf("unknown request type is not reported as unknown:\n%s", body)
+	}
+}
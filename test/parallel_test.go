@@ -0,0 +1,87 @@
+package main_test
+
+import (
+	"bytes"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	qp "github.com/quic-s/quics-protocol"
+	qpStream "github.com/quic-s/quics-protocol/pkg/stream"
+	"github.com/quic-s/quics-protocol/pkg/types/fileinfo"
+	pb "github.com/quic-s/quics-protocol/proto/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestSendFileParallel(t *testing.T) {
+	rnd := rand.New(rand.NewSource(3))
+	data := make([]byte, 5<<20+123)
+	rnd.Read(data)
+
+	dir := t.TempDir()
+	srcPath := filepath.Join(dir, "src")
+	dstPath := filepath.Join(dir, "dst")
+	err := os.WriteFile(srcPath, data, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serverErr, clientErr := transact(t, 18097, func(conn *qp.Connection, stream *qp.Stream) error {
+		_, err := conn.RecvFileParallel(stream, dstPath)
+		return err
+	}, func(conn *qp.Connection, stream *qp.Stream) error {
+		return conn.SendFileParallel(stream, srcPath, 2)
+	})
+	if serverErr != nil || clientErr != nil {
+		t.Fatal(serverErr, clientErr)
+	}
+
+	received, err := os.ReadFile(dstPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(received, data) {
+		t.Fatal("received file is not equal to the sent file")
+	}
+}
+
+func TestRecvFileParallelRejectsInvalidRanges(t *testing.T) {
+	dstPath := filepath.Join(t.TempDir(), "dst")
+
+	// A range size of 1 byte for a huge file would make the receiving side allocate a hash for each byte.
+	serverErr, _ := transact(t, 18098, func(conn *qp.Connection, stream *qp.Stream) error {
+		_, err := conn.RecvFileParallel(stream, dstPath)
+		return err
+	}, func(conn *qp.Connection, stream *qp.Stream) error {
+		err := qpStream.WriteHeader(stream, pb.RequestType_PARALLEL_FILE, []byte("request"), "")
+		if err != nil {
+			return err
+		}
+		err = qpStream.WriteFileInfo(stream, &fileinfo.FileInfo{
+			Name:    "dst",
+			Size:    1 << 40,
+			Mode:    0644,
+			ModTime: time.Now(),
+		})
+		if err != nil {
+			return err
+		}
+		parallelFile, err := proto.Marshal(&pb.ParallelFile{
+			TransferID: []byte("transfer"),
+			RangeSize:  1,
+			RangeCount: 1 << 40,
+		})
+		if err != nil {
+			return err
+		}
+		return qpStream.WriteMessage(stream, parallelFile)
+	})
+	if serverErr == nil || serverErr.Error() != "invalid parallel file" {
+		t.Fatal("invalid parallel file is not rejected:", serverErr)
+	}
+	if _, err := os.Stat(dstPath); !os.IsNotExist(err) {
+		t.Fatal("file is created for an invalid parallel file")
+	}
+}
@@ -0,0 +1,112 @@
+package main_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	qp "github.com/quic-s/quics-protocol"
+	"github.com/quic-s/quics-protocol/pkg/ratelimit"
+)
+
+func TestLimiterWaitCanceled(t *testing.T) {
+	limiter := ratelimit.New(1000, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := limiter.WaitN(ctx, 10000)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatal("wait is not canceled:", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatal("wait is canceled too late:", elapsed)
+	}
+}
+
+func TestLimiterSetLimitWakesWaiters(t *testing.T) {
+	parent := ratelimit.New(1000, nil)
+	limiter := ratelimit.New(0, parent)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- limiter.WaitN(context.Background(), 10000)
+	}()
+	time.Sleep(100 * time.Millisecond)
+	parent.SetLimit(0)
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("wait is not woken up by the new limit")
+	}
+}
+
+func TestUploadLimitIncludesHeaders(t *testing.T) {
+	const messages = 40
+
+	start := time.Now()
+	serverErr, clientErr := transact(t, 18099, func(conn *qp.Connection, stream *qp.Stream) error {
+		for i := 0; i < messages; i++ {
+			_, err := stream.RecvBMessage()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}, func(conn *qp.Connection, stream *qp.Stream) error {
+		// Each message is a byte, so the rate is limited mostly by the headers.
+		stream.SetUploadLimit(1000)
+		for i := 0; i < messages; i++ {
+			err := stream.SendBMessage([]byte{byte(i)})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if serverErr != nil || clientErr != nil {
+		t.Fatal(serverErr, clientErr)
+	}
+	// The transact helper waits 200ms for the server to listen.
+	if elapsed := time.Since(start) - 200*time.Millisecond; elapsed < 500*time.Millisecond {
+		t.Fatal("headers are not limited by the upload limit:", elapsed)
+	}
+}
+
+func TestDownloadLimitAfterClose(t *testing.T) {
+	response := make([]byte, 64<<10)
+	serverErr, clientErr := transact(t, 18115, func(conn *qp.Connection, stream *qp.Stream) error {
+		_, err := stream.RecvBMessage()
+		if err != nil {
+			return err
+		}
+		return stream.SendBMessage(response)
+	}, func(conn *qp.Connection, stream *qp.Stream) error {
+		stream.SetDownloadLimit(32 << 10)
+		err := stream.SendBMessage([]byte("request"))
+		if err != nil {
+			return err
+		}
+		// The response is read after this side of the stream is closed, and it waits for the download limit.
+		err = stream.Stream.Close()
+		if err != nil {
+			return err
+		}
+		data, err := stream.RecvBMessage()
+		if err != nil {
+			return err
+		}
+		if len(data) != len(response) {
+			return errors.New("response is not received")
+		}
+		return nil
+	})
+	if serverErr != nil || clientErr != nil {
+		t.Fatal(serverErr, clientErr)
+	}
+}
@@ -0,0 +1,113 @@
+package main_test
+
+import (
+	"crypto/tls"
+	"path/filepath"
+	"testing"
+	"time"
+
+	qp "github.com/quic-s/quics-protocol"
+)
+
+func TestReloadCertificate(t *testing.T) {
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, "key.pem")
+	certPath := filepath.Join(dir, "cert.pem")
+	generate := func() *tls.Certificate {
+		cert, err := qp.GenerateCertificate(qp.DefaultCertificateOptions)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cert
+	}
+	first := generate()
+	err := qp.WriteCertificate(first, keyPath, certPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reloader, err := qp.NewReloader(keyPath, certPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reloader.Close()
+	reloadErrs := make(chan error, 1)
+	reloader.SetErrorFunc(func(err error) {
+		reloadErrs <- err
+	})
+	reloader.Watch(20 * time.Millisecond)
+
+	server, err := qp.New(qp.LOG_LEVEL_ERROR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Listen(":18089", &tls.Config{GetCertificate: reloader.GetCertificate, NextProtos: []string{"quics-protocol"}}, func(conn *qp.Connection) {})
+	defer server.Close()
+	time.Sleep(200 * time.Millisecond)
+
+	serverFingerprint := func() string {
+		client, err := qp.New(qp.LOG_LEVEL_ERROR)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn, err := client.Dial("localhost", 18089, &tls.Config{InsecureSkipVerify: true, NextProtos: []string{"quics-protocol"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		return conn.PeerIdentity().Fingerprint
+	}
+	if serverFingerprint() != qp.Fingerprint(first.Leaf) {
+		t.Fatal("server does not use the first certificate")
+	}
+
+	second := generate()
+	err = qp.WriteCertificate(second, keyPath, certPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	if serverFingerprint() != qp.Fingerprint(second.Leaf) {
+		t.Fatal("server does not use the reloaded certificate")
+	}
+
+	// A key that does not match the certificate is not swapped in.
+	third := generate()
+	third.Certificate = second.Certificate
+	err = qp.WriteCertificate(third, keyPath, filepath.Join(dir, "unused.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-reloadErrs:
+	case <-time.After(2 * time.Second):
+		t.Fatal("reload failure is not reported")
+	}
+	if qp.Fingerprint(reloader.Certificate().Leaf) != qp.Fingerprint(second.Leaf) {
+		t.Fatal("mismatched pair is swapped in")
+	}
+}
+
+func TestReloaderWatchDefaultInterval(t *testing.T) {
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, "key.pem")
+	certPath := filepath.Join(dir, "cert.pem")
+	cert, err := qp.GenerateCertificate(qp.DefaultCertificateOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = qp.WriteCertificate(cert, keyPath, certPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reloader, err := qp.NewReloader(keyPath, certPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reloader.Close()
+
+	// An interval of 0 or less uses the default interval instead of making the ticker panic.
+	reloader.Watch(0)
+	reloader.Watch(-time.Second)
+	time.Sleep(50 * time.Millisecond)
+}
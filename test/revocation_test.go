@@ -0,0 +1,129 @@
+package main_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/x509"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	qp "github.com/quic-s/quics-protocol"
+)
+
+func TestRevocationCRL(t *testing.T) {
+	ca, err := qp.NewRootCA(qp.CertificateOptions{CommonName: "root", Validity: 24 * time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	issue := func() *x509.Certificate {
+		cert, err := ca.IssueClient(qp.CertificateOptions{CommonName: "client", Validity: time.Hour})
+		if err != nil {
+			t.Fatal(err)
+		}
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return leaf
+	}
+	revoked, valid := issue(), issue()
+	newCRL := func(nextUpdate time.Time) *x509.RevocationList {
+		crlBytes, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+			Number:     big.NewInt(time.Now().UnixNano()),
+			ThisUpdate: time.Now().Add(-2 * time.Hour),
+			NextUpdate: nextUpdate,
+			RevokedCertificateEntries: []x509.RevocationListEntry{
+				{SerialNumber: revoked.SerialNumber, RevocationTime: time.Now()},
+			},
+		}, ca.Certificate, ca.Key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		crl, err := x509.ParseRevocationList(crlBytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return crl
+	}
+
+	revocation := qp.NewRevocation()
+	revocation.AddCRL(newCRL(time.Now().Add(time.Hour)))
+	if err := revocation.Check([]*x509.Certificate{revoked}); !errors.Is(err, qp.ErrCertificateRevoked) {
+		t.Fatal("revoked certificate is accepted:", err)
+	}
+	if err := revocation.Check([]*x509.Certificate{valid}); err != nil {
+		t.Fatal("valid certificate is rejected:", err)
+	}
+
+	// An expired CRL may miss the certificates revoked since, so the certificates of its issuer are rejected.
+	revocation.AddCRL(newCRL(time.Now().Add(-time.Hour)))
+	if err := revocation.Check([]*x509.Certificate{valid}); !errors.Is(err, qp.ErrCRLExpired) {
+		t.Fatal("certificate is accepted with an expired CRL:", err)
+	}
+}
+
+func TestAdmissionRevocationVerifiedChains(t *testing.T) {
+	ca, err := qp.NewRootCA(qp.CertificateOptions{CommonName: "root", Validity: 24 * time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverCert, err := ca.IssueServer(qp.CertificateOptions{CommonName: "server", Hosts: []string{"localhost"}, Validity: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientCert, err := ca.IssueClient(qp.CertificateOptions{CommonName: "client", Validity: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := qp.NewRootCA(qp.CertificateOptions{CommonName: "other", Validity: 24 * time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientLeaf, err := x509.ParseCertificate(clientCert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	revocation := qp.NewRevocation()
+	// The certificate of the other CA is banned, but it is not a part of the verified chain.
+	revocation.Ban(qp.Fingerprint(other.Certificate))
+	server, err := qp.New(qp.LOG_LEVEL_ERROR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.SetAdmission(qp.Admission{Revocation: revocation})
+	go server.Listen(":18106", ca.ServerTLSConfig(serverCert), func(conn *qp.Connection) {})
+	defer server.Close()
+	time.Sleep(200 * time.Millisecond)
+
+	rejected := func() bool {
+		client, err := qp.New(qp.LOG_LEVEL_ERROR)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The unrelated certificate is sent after the client certificate.
+		cert := *clientCert
+		cert.Certificate = append(append([][]byte{}, clientCert.Certificate...), other.Certificate.Raw)
+		conn, err := client.Dial("localhost", 18106, ca.ClientTLSConfig(&cert, "localhost"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		_, err = conn.Conn.AcceptStream(ctx)
+		appErr := &quic.ApplicationError{}
+		return errors.As(err, &appErr) && appErr.ErrorCode == qp.ConnectionRejectedCode
+	}
+
+	if rejected() {
+		t.Fatal("connection is rejected by a certificate out of the verified chain")
+	}
+	revocation.Ban(qp.Fingerprint(clientLeaf))
+	if !rejected() {
+		t.Fatal("connection with a revoked client certificate is not rejected")
+	}
+}
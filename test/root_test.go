@@ -0,0 +1,55 @@
+package main_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	qp "github.com/quic-s/quics-protocol"
+	"github.com/quic-s/quics-protocol/pkg/root"
+)
+
+func TestRootConfinesWrites(t *testing.T) {
+	dir := t.TempDir()
+	outside := filepath.Join(dir, "outside")
+	err := os.Mkdir(outside, 0700)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := root.New(filepath.Join(dir, "root"), root.DefaultUmask)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Symlink(outside, filepath.Join(r.Dir(), "link"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"../escape.txt", "a/../../escape.txt", "/tmp/escape.txt", "link/escape.txt"} {
+		fileInfo := &qp.FileInfo{Name: name, Size: 4, Mode: 0600, ModTime: time.Now()}
+		err = r.WriteFile(fileInfo, bytes.NewReader([]byte("evil")), nil)
+		if !errors.Is(err, root.ErrPathEscape) {
+			t.Fatalf("%s: path escape error is expected: %v", name, err)
+		}
+	}
+	entries, _ := os.ReadDir(outside)
+	if len(entries) != 0 {
+		t.Fatal("file is written outside of the root")
+	}
+
+	fileInfo := &qp.FileInfo{Name: "a/b/run.sh", Size: 4, Mode: 0755 | os.ModeSetuid, ModTime: time.Now()}
+	err = r.WriteFile(fileInfo, bytes.NewReader([]byte("echo")), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stat, err := os.Stat(filepath.Join(r.Dir(), "a", "b", "run.sh"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat.Mode() != 0755 {
+		t.Fatalf("setuid bit is not masked: %s", stat.Mode())
+	}
+}
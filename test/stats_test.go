@@ -0,0 +1,101 @@
+package main_test
+
+import (
+	"crypto/tls"
+	"testing"
+	"time"
+
+	qp "github.com/quic-s/quics-protocol"
+)
+
+func TestStats(t *testing.T) {
+	server, err := qp.New(qp.LOG_LEVEL_ERROR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = server.RecvTransactionHandleFunc("echo", func(conn *qp.Connection, stream *qp.Stream, transactionName string, transactionID []byte) error {
+		data, err := stream.RecvBMessage()
+		if err != nil {
+			return err
+		}
+		return stream.SendBMessage(data)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := qp.GetCertificate("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Listen(":18094", &tls.Config{Certificates: cert, NextProtos: []string{"quics-protocol"}}, func(conn *qp.Connection) {})
+	defer server.Close()
+	time.Sleep(200 * time.Millisecond)
+
+	client, err := qp.New(qp.LOG_LEVEL_ERROR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetPingInterval(50 * time.Millisecond)
+	conn, err := client.Dial("localhost", 18094, &tls.Config{InsecureSkipVerify: true, NextProtos: []string{"quics-protocol"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	message := make([]byte, 1000)
+	for i := 0; i < 3; i++ {
+		err = conn.OpenTransaction("echo", func(stream *qp.Stream, transactionName string, transactionID []byte) error {
+			err := stream.SendBMessage(message)
+			if err != nil {
+				return err
+			}
+			_, err = stream.RecvBMessage()
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats := conn.Stats()
+	if stats.CompletedTransactions != 3 || stats.OpenTransactions != 0 {
+		t.Fatalf("3 completed transactions are expected, but got %+v", stats)
+	}
+	if stats.BytesSent < 3*1000 || stats.BytesReceived < 3*1000 {
+		t.Fatalf("bytes of the messages are not counted: %+v", stats)
+	}
+	// Each transaction sends the transaction handshake and a message.
+	if stats.RequestsSent < 6 || stats.RequestsReceived < 6 {
+		t.Fatalf("requests are not counted: %+v", stats)
+	}
+	if stats.Age <= 0 {
+		t.Fatalf("wrong connection age %v", stats.Age)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for conn.Stats().SmoothedRTT == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("round-trip time is not measured by the pings")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	stats = conn.Stats()
+	if stats.RTT <= 0 || stats.MinRTT <= 0 || stats.MinRTT > stats.RTT {
+		t.Fatalf("wrong round-trip times: %+v", stats)
+	}
+
+	deadline = time.Now().Add(2 * time.Second)
+	for {
+		aggregate := server.Stats()
+		if aggregate.Connections == 1 && aggregate.CompletedTransactions == 3 {
+			if aggregate.BytesReceived < 3*1000 {
+				t.Fatalf("bytes of the messages are not counted on the server: %+v", aggregate)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("1 connection with 3 completed transactions is expected on the server, but got %+v", aggregate)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
@@ -0,0 +1,162 @@
+package main_test
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	qp "github.com/quic-s/quics-protocol"
+)
+
+func TestHandshakeTimeout(t *testing.T) {
+	server, err := qp.New(qp.LOG_LEVEL_ERROR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.SetTimeouts(qp.Timeouts{
+		HandshakeTimeout:         200 * time.Millisecond,
+		MaxTimeoutsPerConnection: 2,
+	})
+	cert, err := qp.GetCertificate("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Listen(":18082", &tls.Config{Certificates: cert, NextProtos: []string{"quics-protocol"}}, func(conn *qp.Connection) {})
+	defer server.Close()
+	time.Sleep(200 * time.Millisecond)
+
+	client, err := qp.New(qp.LOG_LEVEL_ERROR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := client.Dial("localhost", 18082, &tls.Config{InsecureSkipVerify: true, NextProtos: []string{"quics-protocol"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// Open streams and stay silent after the first byte of the header.
+	for i := 0; i < 2; i++ {
+		stream, err := conn.Conn.OpenStreamSync(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = stream.Write([]byte{0})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = stream.Read(make([]byte, 1))
+		if i == 0 {
+			streamErr := &quic.StreamError{}
+			if !errors.As(err, &streamErr) || streamErr.ErrorCode != qp.HandshakeTimeoutCode {
+				t.Fatalf("stream reset with handshake timeout code is expected: %v", err)
+			}
+		}
+	}
+
+	select {
+	case <-conn.Conn.Context().Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("connection is not closed after too many timeouts")
+	}
+}
+
+func TestHandshakeTimeoutWithTransaction(t *testing.T) {
+	server, err := qp.New(qp.LOG_LEVEL_ERROR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.SetTimeouts(qp.Timeouts{
+		HandshakeTimeout: 200 * time.Millisecond,
+	})
+	cert, err := qp.GetCertificate("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.ListenWithTransaction(":18116", &tls.Config{Certificates: cert, NextProtos: []string{"quics-protocol"}}, func(conn *qp.Connection, stream *qp.Stream, transactionName string, transactionID []byte) error {
+		return nil
+	})
+	defer server.Close()
+	time.Sleep(200 * time.Millisecond)
+
+	// The client connects without the capabilities exchange, so the first stream is the initial transaction.
+	conn, err := quic.DialAddr(context.Background(), "localhost:18116", &tls.Config{InsecureSkipVerify: true, NextProtos: []string{"quics-protocol"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.CloseWithError(0, "")
+
+	// Open the initial stream and stay silent after the first byte of the header.
+	stream, err := conn.OpenStreamSync(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = stream.Write([]byte{0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, err = stream.Read(make([]byte, 1))
+	streamErr := &quic.StreamError{}
+	if !errors.As(err, &streamErr) || streamErr.ErrorCode != qp.HandshakeTimeoutCode {
+		t.Fatalf("initial stream reset with handshake timeout code is expected: %v", err)
+	}
+}
+
+func TestIdleTimeout(t *testing.T) {
+	server, err := qp.New(qp.LOG_LEVEL_ERROR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.SetTimeouts(qp.Timeouts{
+		IdleTimeout: 200 * time.Millisecond,
+	})
+	serverErr := make(chan error, 1)
+	err = server.RecvTransactionHandleFunc("idle", func(conn *qp.Connection, stream *qp.Stream, transactionName string, transactionID []byte) error {
+		_, err := stream.RecvBMessage()
+		serverErr <- err
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := qp.GetCertificate("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Listen(":18103", &tls.Config{Certificates: cert, NextProtos: []string{"quics-protocol"}}, func(conn *qp.Connection) {})
+	defer server.Close()
+	time.Sleep(200 * time.Millisecond)
+
+	client, err := qp.New(qp.LOG_LEVEL_ERROR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := client.Dial("localhost", 18103, &tls.Config{InsecureSkipVerify: true, NextProtos: []string{"quics-protocol"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// Open the transaction and stay silent, so the server times out waiting for a message.
+	clientErr := conn.OpenTransaction("idle", func(stream *qp.Stream, transactionName string, transactionID []byte) error {
+		_, err := stream.RecvBMessage()
+		return err
+	})
+	streamErr := &quic.StreamError{}
+	if !errors.As(clientErr, &streamErr) || streamErr.ErrorCode != qp.IdleTimeoutCode {
+		t.Fatalf("stream reset with idle timeout code is expected: %v", clientErr)
+	}
+
+	select {
+	case err := <-serverErr:
+		if !errors.Is(err, qp.ErrIdleTimeout) {
+			t.Fatalf("idle timeout error is expected: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("transaction is not timed out")
+	}
+}
@@ -0,0 +1,59 @@
+package main_test
+
+import (
+	"crypto/ed25519"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	qp "github.com/quic-s/quics-protocol"
+)
+
+func TestGetCertificatePersists(t *testing.T) {
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, "tls", "key.pem")
+	certPath := filepath.Join(dir, "tls", "cert.pem")
+	opts := qp.CertificateOptions{
+		KeyType:    qp.KeyEd25519,
+		CommonName: "server",
+		Hosts:      []string{"sync.example.com", "192.0.2.1"},
+		Validity:   48 * time.Hour,
+	}
+
+	first, err := qp.GetCertificateWithOptions(keyPath, certPath, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{keyPath, certPath} {
+		stat, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stat.Mode().Perm() != 0600 {
+			t.Fatalf("%s is written with mode %v", path, stat.Mode().Perm())
+		}
+	}
+	leaf := first[0].Leaf
+	if _, ok := first[0].PrivateKey.(ed25519.PrivateKey); !ok {
+		t.Fatalf("Ed25519 key is expected, but got %T", first[0].PrivateKey)
+	}
+	if len(leaf.DNSNames) != 1 || leaf.DNSNames[0] != "sync.example.com" || len(leaf.IPAddresses) != 1 || leaf.IPAddresses[0].String() != "192.0.2.1" {
+		t.Fatalf("wrong SANs: %v %v", leaf.DNSNames, leaf.IPAddresses)
+	}
+
+	second, err := qp.GetCertificate(keyPath, certPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if qp.Fingerprint(second[0].Leaf) != qp.Fingerprint(leaf) {
+		t.Fatal("certificate is not reused")
+	}
+	expiresIn, err := qp.ExpiresIn(&second[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expiresIn < 47*time.Hour || expiresIn > 48*time.Hour {
+		t.Fatalf("certificate expires in %v", expiresIn)
+	}
+}
@@ -1,9 +1,15 @@
 package qp
 
 import (
+	"github.com/quic-s/quics-protocol/pkg/admission"
+	"github.com/quic-s/quics-protocol/pkg/auth"
 	qpConn "github.com/quic-s/quics-protocol/pkg/connection"
+	"github.com/quic-s/quics-protocol/pkg/enrollment"
 	qpErr "github.com/quic-s/quics-protocol/pkg/error"
+	qpHandler "github.com/quic-s/quics-protocol/pkg/handler"
 	qpLog "github.com/quic-s/quics-protocol/pkg/log"
+	"github.com/quic-s/quics-protocol/pkg/metrics"
+	"github.com/quic-s/quics-protocol/pkg/progress"
 	qpStream "github.com/quic-s/quics-protocol/pkg/stream"
 	"github.com/quic-s/quics-protocol/pkg/tls"
 	"github.com/quic-s/quics-protocol/pkg/types/fileinfo"
@@ -17,10 +23,120 @@ const (
 	ConnectionClosedByPeer = qpErr.ConnectionClosedByPeer
 
 	NoRecentActivity = qpErr.NoRecentActivity
+
+	LimitExceededCode = qpErr.LimitExceededCode
+
+	HandshakeTimeoutCode = qpErr.HandshakeTimeoutCode
+
+	IdleTimeoutCode = qpErr.IdleTimeoutCode
+
+	TooManyTimeoutsCode = qpErr.TooManyTimeoutsCode
+
+	ConnectionRejectedCode = qpErr.ConnectionRejectedCode
+
+	UnauthenticatedCode = qpErr.UnauthenticatedCode
+
+	AuthenticationFailedCode = qpErr.AuthenticationFailedCode
+
+	UnknownTransactionCode = qpErr.UnknownTransactionCode
+
+	KeyECDSAP256 = tls.KeyECDSAP256
+
+	KeyEd25519 = tls.KeyEd25519
+
+	ProtocolVersion = qpConn.ProtocolVersion
+
+	MaxPreAuthTransactions = qpConn.MaxPreAuthTransactions
+
+	MaxSettings = qpConn.MaxSettings
+
+	MaxSettingLength = qpConn.MaxSettingLength
+
+	OutcomeOK = metrics.OutcomeOK
+
+	OutcomeError = metrics.OutcomeError
+
+	OutcomeRefused = metrics.OutcomeRefused
+
+	DefaultWatchInterval = tls.DefaultWatchInterval
 )
 
 var (
 	GetCertificate = tls.GetCertificate
+
+	GetCertificateWithOptions = tls.GetCertificateWithOptions
+
+	GenerateCertificate = tls.GenerateCertificate
+
+	WriteCertificate = tls.WriteCertificate
+
+	ExpiresIn = tls.ExpiresIn
+
+	DefaultCertificateOptions = tls.DefaultCertificateOptions
+
+	NewRootCA = tls.NewRootCA
+
+	LoadCA = tls.LoadCA
+
+	LoadKnownHosts = tls.LoadKnownHosts
+
+	NewReloader = tls.NewReloader
+
+	NewBootstrapTokens = enrollment.NewBootstrapTokens
+
+	DefaultCapabilities = qpConn.DefaultCapabilities
+
+	Negotiate = qpConn.Negotiate
+
+	NewPrometheus = metrics.NewPrometheus
+
+	ErrUnknownHost = tls.ErrUnknownHost
+
+	ErrInvalidKnownHost = tls.ErrInvalidKnownHost
+
+	DefaultLimits = qpStream.DefaultLimits
+
+	DefaultTimeouts = qpStream.DefaultTimeouts
+
+	ErrHandshakeTimeout = qpErr.ErrHandshakeTimeout
+
+	ErrIdleTimeout = qpErr.ErrIdleTimeout
+
+	ErrConnectionDenied = qpErr.ErrConnectionDenied
+
+	ErrTooManyConnections = qpErr.ErrTooManyConnections
+
+	ParseCIDRs = admission.ParseCIDRs
+
+	NewRevocation = tls.NewRevocation
+
+	Fingerprint = tls.Fingerprint
+
+	LoadCertPool = tls.LoadCertPool
+
+	ErrCertificateRevoked = tls.ErrCertificateRevoked
+
+	ErrCRLExpired = tls.ErrCRLExpired
+
+	ErrUnauthenticated = qpErr.ErrUnauthenticated
+
+	ErrAuthenticationFailed = qpErr.ErrAuthenticationFailed
+
+	ErrGoingAway = qpErr.ErrGoingAway
+
+	ErrNoControlStream = qpErr.ErrNoControlStream
+
+	NewTokenAuthenticator = auth.NewTokenAuthenticator
+
+	NewTokenCredential = auth.NewTokenCredential
+
+	NewHMACAuthenticator = auth.NewHMACAuthenticator
+
+	NewHMACCredential = auth.NewHMACCredential
+
+	NewCallbackAuthenticator = auth.NewCallbackAuthenticator
+
+	NewCallbackCredential = auth.NewCallbackCredential
 )
 
 type Connection = qpConn.Connection
@@ -28,3 +144,51 @@ type Connection = qpConn.Connection
 type Stream = qpStream.Stream
 
 type FileInfo = fileinfo.FileInfo
+
+type Progress = progress.Progress
+
+type Limits = qpStream.Limits
+
+type LimitError = qpErr.LimitError
+
+type Timeouts = qpStream.Timeouts
+
+type Concurrency = qpHandler.Concurrency
+
+type Admission = admission.Admission
+
+type ConnectionInfo = admission.ConnectionInfo
+
+type CertificateOptions = tls.CertificateOptions
+
+type CA = tls.CA
+
+type KnownHosts = tls.KnownHosts
+
+type Reloader = tls.Reloader
+
+type Enrollment = enrollment.Enrollment
+
+type EnrollmentRequest = enrollment.Request
+
+type Capabilities = qpConn.Capabilities
+
+type GoAway = qpConn.GoAway
+
+type ConnectionStats = qpConn.Stats
+
+type AggregateStats = qpConn.AggregateStats
+
+type Metrics = metrics.Metrics
+
+type Prometheus = metrics.Prometheus
+
+type HostKeyMismatchError = tls.HostKeyMismatchError
+
+type Identity = tls.Identity
+
+type Revocation = tls.Revocation
+
+type Authenticator = auth.Authenticator
+
+type Credential = auth.Credential